@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// copyVerified copies src to dst and verifies the copy against src.
+// It is used when os.Rename cannot move src, because dst is on a different volume.
+// On failure the partial copy is removed.
+func copyVerified(src string, dst string) error {
+	if err := copyTree(src, dst); err != nil {
+		os.RemoveAll(dst)
+		return err
+	}
+	if err := verifyTree(src, dst); err != nil {
+		os.RemoveAll(dst)
+		return err
+	}
+	return nil
+}
+
+// copyTree copies src to dst recursively, preserving mode, modification time,
+// symbolic links and, where permitted, ownership.
+func copyTree(src string, dst string) error {
+	st, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case st.Mode()&os.ModeSymlink != 0:
+		target, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+		if err := os.Symlink(target, dst); err != nil {
+			return err
+		}
+	case st.IsDir():
+		// Keep the directory writable until its entries have been copied
+		if err := os.Mkdir(dst, 0700); err != nil {
+			return err
+		}
+		entries, err := os.ReadDir(src)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if err := copyTree(filepath.Join(src, e.Name()), filepath.Join(dst, e.Name())); err != nil {
+				return err
+			}
+		}
+	case st.Mode().IsRegular():
+		if err := copyFile(src, dst, st.Mode().Perm()); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("%s: cannot copy special file across devices", src)
+	}
+
+	// Ownership can only be kept by privileged users, so errors are ignored.
+	if sys, ok := st.Sys().(*syscall.Stat_t); ok {
+		os.Lchown(dst, int(sys.Uid), int(sys.Gid))
+	}
+	if st.Mode()&os.ModeSymlink != 0 {
+		return nil
+	}
+	if err := os.Chmod(dst, st.Mode()&(os.ModePerm|os.ModeSetuid|os.ModeSetgid|os.ModeSticky)); err != nil {
+		return err
+	}
+	return os.Chtimes(dst, st.ModTime(), st.ModTime())
+}
+
+func copyFile(src string, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// verifyTree checks that dst has the same entries, types, sizes and link targets as src.
+func verifyTree(src string, dst string) error {
+	return filepath.Walk(src, func(path string, sst os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		dstat, err := os.Lstat(target)
+		if err != nil {
+			return fmt.Errorf("verify copy: %w", err)
+		}
+		if sst.Mode().Type() != dstat.Mode().Type() {
+			return fmt.Errorf("verify copy: %s has a different type", target)
+		}
+		switch {
+		case sst.Mode().IsRegular():
+			if sst.Size() != dstat.Size() {
+				return fmt.Errorf("verify copy: %s has a different size", target)
+			}
+		case sst.Mode()&os.ModeSymlink != 0:
+			sl, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			dl, err := os.Readlink(target)
+			if err != nil {
+				return err
+			}
+			if sl != dl {
+				return fmt.Errorf("verify copy: %s points to a different target", target)
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// makeTree creates a directory with a file, a read-only subdirectory and a symbolic link under dir.
+func makeTree(t *testing.T, dir string) string {
+	t.Helper()
+	src := filepath.Join(dir, "src")
+	if err := os.MkdirAll(filepath.Join(src, "ro"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "file"), []byte("content"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "ro", "inner"), []byte("inner"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("file", filepath.Join(src, "link")); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := os.Chtimes(filepath.Join(src, "file"), mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(filepath.Join(src, "ro"), 0500); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(filepath.Join(src, "ro"), 0700) })
+	return src
+}
+
+func TestCopyVerified(t *testing.T) {
+	dir := t.TempDir()
+	src := makeTree(t, dir)
+	dst := filepath.Join(dir, "dst")
+	if err := copyVerified(src, dst); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(filepath.Join(dst, "ro"), 0700) })
+
+	if content, err := os.ReadFile(filepath.Join(dst, "ro", "inner")); err != nil || string(content) != "inner" {
+		t.Errorf("ro/inner = %q, %v", content, err)
+	}
+	for _, name := range []string{"file", "ro"} {
+		sst, err := os.Stat(filepath.Join(src, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		dst, err := os.Stat(filepath.Join(dst, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sst.Mode() != dst.Mode() || !sst.ModTime().Equal(dst.ModTime()) {
+			t.Errorf("%s: copy has mode %v and mtime %v, want %v and %v", name, dst.Mode(), dst.ModTime(), sst.Mode(), sst.ModTime())
+		}
+	}
+	if target, err := os.Readlink(filepath.Join(dst, "link")); err != nil || target != "file" {
+		t.Errorf("link = %q, %v, want a symbolic link to file", target, err)
+	}
+	if _, err := os.Lstat(filepath.Join(src, "file")); err != nil {
+		t.Errorf("source was touched: %v", err)
+	}
+}
+
+// A copy that fails part of the way must not be left behind.
+func TestCopyVerifiedRemovesPartialCopy(t *testing.T) {
+	dir := t.TempDir()
+	src := makeTree(t, dir)
+	if err := syscall.Mkfifo(filepath.Join(src, "zfifo"), 0600); err != nil {
+		t.Skip("cannot create a FIFO: ", err)
+	}
+	dst := filepath.Join(dir, "dst")
+	if err := copyVerified(src, dst); err == nil {
+		t.Error("copyVerified copied a FIFO")
+	}
+	if _, err := os.Lstat(dst); !os.IsNotExist(err) {
+		t.Errorf("partial copy was left at %s: %v", dst, err)
+	}
+}
+
+func TestVerifyTreeDetectsDifferences(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(dst string) error
+	}{
+		{"size", func(dst string) error { return os.WriteFile(filepath.Join(dst, "file"), []byte("changed!"), 0640) }},
+		{"missing", func(dst string) error { return os.Remove(filepath.Join(dst, "link")) }},
+		{"type", func(dst string) error {
+			if err := os.Remove(filepath.Join(dst, "link")); err != nil {
+				return err
+			}
+			return os.WriteFile(filepath.Join(dst, "link"), []byte("file"), 0600)
+		}},
+		{"link target", func(dst string) error {
+			if err := os.Remove(filepath.Join(dst, "link")); err != nil {
+				return err
+			}
+			return os.Symlink("ro", filepath.Join(dst, "link"))
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			src := makeTree(t, dir)
+			dst := filepath.Join(dir, "dst")
+			if err := copyTree(src, dst); err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { os.Chmod(filepath.Join(dst, "ro"), 0700) })
+			if err := verifyTree(src, dst); err != nil {
+				t.Fatalf("verifyTree of an exact copy: %v", err)
+			}
+			if err := tt.change(dst); err != nil {
+				t.Fatal(err)
+			}
+			if err := verifyTree(src, dst); err == nil {
+				t.Error("verifyTree did not notice the change")
+			}
+		})
+	}
+}
+
+// Put copies items it cannot rename into the trash, and removes them once the copy is verified.
+func TestPutCopiesAcrossVolumes(t *testing.T) {
+	trash := newFreedesktopTrash(t.TempDir())
+	dir, err := os.MkdirTemp("/dev/shm", "go-trash-test")
+	if err != nil {
+		t.Skip("/dev/shm is not available")
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	if same, err := sameDevice(dir, filepath.Dir(trash.base)); err != nil || same {
+		t.Skip("/dev/shm is not a separate volume")
+	}
+
+	src := makeTree(t, dir)
+	b := &freedesktop{home: trash}
+	file, err := b.Put(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Lstat(src); !os.IsNotExist(err) {
+		t.Errorf("%s was not removed after the copy: %v", src, err)
+	}
+	if content, err := os.ReadFile(filepath.Join(file.inTrashBox, "ro", "inner")); err != nil || string(content) != "inner" {
+		t.Errorf("trashed ro/inner = %q, %v", content, err)
+	}
+
+	if err := b.Restore(file, src); err != nil {
+		t.Fatal(err)
+	}
+	if content, err := os.ReadFile(filepath.Join(src, "file")); err != nil || string(content) != "content" {
+		t.Errorf("restored file = %q, %v", content, err)
+	}
+}
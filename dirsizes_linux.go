@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// Directory size cache
+// https://specifications.freedesktop.org/trash-spec/trashspec-1.0.html#directorysizes
+//
+// Each line of $trash/directorysizes is "size mtime percent-encoded-name",
+// where mtime is the modification time of the item's .trashinfo file.
+type dirSize struct {
+	size  int64
+	mtime int64
+}
+
+func (t *freedesktopTrash) directorySizesPath() string {
+	return filepath.Join(t.base, "directorysizes")
+}
+
+// readDirectorySizes returns the cached sizes keyed by trash name.
+// A missing or malformed cache is not an error, it is simply rebuilt.
+func (t *freedesktopTrash) readDirectorySizes() map[string]dirSize {
+	sizes := map[string]dirSize{}
+
+	f, err := os.Open(t.directorySizesPath())
+	if err != nil {
+		return sizes
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.SplitN(scanner.Text(), " ", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		size, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		mtime, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		sizes[unescapeTrashPath(fields[2])] = dirSize{size, mtime}
+	}
+	return sizes
+}
+
+// writeDirectorySizes replaces the cache atomically, so readers never see a partial file.
+func (t *freedesktopTrash) writeDirectorySizes(sizes map[string]dirSize) error {
+	tmp, err := os.CreateTemp(t.base, "directorysizes.*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	for name, ds := range sizes {
+		fmt.Fprintf(w, "%d %d %s\n", ds.size, ds.mtime, escapeTrashPath(name))
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), t.directorySizesPath())
+}
+
+// updateDirectorySize caches the size of the trashed directory trashName,
+// or drops it from the cache if it is no longer in the trash.
+func (t *freedesktopTrash) updateDirectorySize(trashName string) error {
+	sizes := t.readDirectorySizes()
+
+	st, err := os.Lstat(filepath.Join(t.filesDir(), trashName))
+	if err == nil && st.IsDir() {
+		ist, err := os.Stat(t.infoPath(trashName))
+		if err != nil {
+			return err
+		}
+		sizes[trashName] = dirSize{treeSize(filepath.Join(t.filesDir(), trashName)), ist.ModTime().Unix()}
+	} else if _, ok := sizes[trashName]; ok {
+		delete(sizes, trashName)
+	} else {
+		return nil
+	}
+	return t.writeDirectorySizes(sizes)
+}
+
+// treeSize returns the disk usage of path and everything under it, as du -B1 reports it:
+// the blocks allocated to files, symbolic links and the directories themselves,
+// counting a file with several hard links once. The spec defines directorysizes this way,
+// so other tools reading the cache see the same numbers.
+func treeSize(path string) int64 {
+	var total int64
+	seen := map[[2]uint64]bool{}
+	filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		st, ok := info.Sys().(*syscall.Stat_t)
+		if !ok {
+			return nil
+		}
+		if st.Nlink > 1 && !d.IsDir() {
+			inode := [2]uint64{uint64(st.Dev), st.Ino}
+			if seen[inode] {
+				return nil
+			}
+			seen[inode] = true
+		}
+		total += st.Blocks * 512
+		return nil
+	})
+	return total
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTreeSizeIsDiskUsage(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "d")
+	if err := os.MkdirAll(filepath.Join(dir, "sub"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "sub", "data"), []byte(strings.Repeat("x", 10000)), 0600); err != nil {
+		t.Fatal(err)
+	}
+	// A sparse file takes up almost no space
+	sparse, err := os.Create(filepath.Join(dir, "sparse"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sparse.Truncate(1 << 30); err != nil {
+		t.Fatal(err)
+	}
+	sparse.Close()
+	// Hard links share their blocks
+	if err := os.Link(filepath.Join(dir, "sub", "data"), filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("sub/data", filepath.Join(dir, "symlink")); err != nil {
+		t.Fatal(err)
+	}
+
+	size := treeSize(dir)
+	if size < 10000 || size >= 1<<30 {
+		t.Errorf("treeSize = %d, want the disk usage of about 10000 bytes of data", size)
+	}
+
+	du, err := exec.Command("du", "-s", "-B1", dir).Output()
+	if err != nil {
+		t.Skip("du is not available: ", err)
+	}
+	want, err := strconv.ParseInt(strings.Fields(string(du))[0], 10, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != want {
+		t.Errorf("treeSize = %d, want %d as du -B1 reports", size, want)
+	}
+}
+
+func TestDirectorySizesRoundTrip(t *testing.T) {
+	trash := newFreedesktopTrash(t.TempDir())
+	sizes := map[string]dirSize{
+		"plain":  {4096, 1700000000},
+		"my dir": {8192, 1700000001},
+		"a\nb%+": {0, 1700000002},
+	}
+	if err := trash.writeDirectorySizes(sizes); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(trash.directorySizesPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), "8192 1700000001 my%20dir\n") {
+		t.Errorf("directorysizes = %q, want the name percent-encoded", content)
+	}
+
+	// Malformed lines are skipped
+	f, err := os.OpenFile(trash.directorySizesPath(), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("garbage\nx 1 name\n1 y name\n")
+	f.Close()
+
+	got := trash.readDirectorySizes()
+	if len(got) != len(sizes) {
+		t.Errorf("readDirectorySizes() = %v, want %v", got, sizes)
+	}
+	for name, want := range sizes {
+		if got[name] != want {
+			t.Errorf("size of %q = %v, want %v", name, got[name], want)
+		}
+	}
+}
+
+func TestDirectorySizesCache(t *testing.T) {
+	trash := newFreedesktopTrash(t.TempDir())
+	b := &freedesktop{home: trash}
+	dir := filepath.Join(t.TempDir(), "my dir")
+	if err := os.Mkdir(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "data"), []byte(strings.Repeat("x", 10000)), 0600); err != nil {
+		t.Fatal(err)
+	}
+	file, err := b.Put(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Put caches the size of a trashed directory
+	ds, ok := trash.readDirectorySizes()["my dir"]
+	if !ok || ds.size != treeSize(file.inTrashBox) {
+		t.Fatalf("cached %v, %v, want the size %d", ds, ok, treeSize(file.inTrashBox))
+	}
+	ist, err := os.Stat(trash.infoPath("my dir"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ds.mtime != ist.ModTime().Unix() {
+		t.Errorf("cached mtime %d, want the mtime %d of the .trashinfo", ds.mtime, ist.ModTime().Unix())
+	}
+
+	// List uses the cached size while the .trashinfo is unchanged
+	if err := trash.writeDirectorySizes(map[string]dirSize{"my dir": {12345, ds.mtime}}); err != nil {
+		t.Fatal(err)
+	}
+	if files, err := b.List(); err != nil || len(files) != 1 || files[0].size != 12345 {
+		t.Errorf("List() = %v, %v, want the cached size 12345", files, err)
+	}
+
+	// and computes it again when the .trashinfo has changed
+	mtime := time.Unix(ds.mtime+60, 0)
+	if err := os.Chtimes(trash.infoPath("my dir"), mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	files, err := b.List()
+	if err != nil || len(files) != 1 || files[0].size != ds.size {
+		t.Fatalf("List() = %v, %v, want the size %d", files, err, ds.size)
+	}
+	if got := trash.readDirectorySizes()["my dir"]; got != (dirSize{ds.size, mtime.Unix()}) {
+		t.Errorf("cache after List() = %v, want it updated", got)
+	}
+
+	// Purge drops the directory from the cache
+	if err := b.Purge(files[0]); err != nil {
+		t.Fatal(err)
+	}
+	if sizes := trash.readDirectorySizes(); len(sizes) != 0 {
+		t.Errorf("cache after purge = %v, want it empty", sizes)
+	}
+}
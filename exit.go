@@ -0,0 +1,38 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Exit codes of go-trash
+const (
+	exitOK      = 0 // everything was done, or there was nothing to do
+	exitPartial = 1 // some paths or items failed, the others were processed
+	exitUsage   = 2 // invalid options or parameters
+	exitFailure = 3 // nothing could be done
+)
+
+// partialError reports how many items of an operation failed.
+// Each failure has already been reported with its path.
+type partialError struct {
+	op     string // trash, restore or purge
+	failed int
+	total  int
+}
+
+func (e *partialError) Error() string {
+	return fmt.Sprintf("failed to %s %d of %d items", e.op, e.failed, e.total)
+}
+
+// exitCode returns the exit code for the error an operation returned.
+func exitCode(err error) int {
+	var partial *partialError
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.As(err, &partial) && partial.failed < partial.total:
+		return exitPartial
+	}
+	return exitFailure
+}
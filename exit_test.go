@@ -0,0 +1,26 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"success", nil, exitOK},
+		{"some failed", &partialError{"trash", 1, 3}, exitPartial},
+		{"all failed", &partialError{"trash", 3, 3}, exitFailure},
+		{"wrapped partial", fmt.Errorf("undo: %w", &partialError{"restore", 1, 2}), exitPartial},
+		{"other error", errors.New("no trashed file matches x"), exitFailure},
+	}
+	for _, tt := range tests {
+		if got := exitCode(tt.err); got != tt.want {
+			t.Errorf("%s: exitCode(%v) = %d, want %d", tt.name, tt.err, got, tt.want)
+		}
+	}
+}
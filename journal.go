@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// The journal records each batch of files trashed by one go-trash command, so it can be undone.
+// It is a JSON Lines file, oldest batch first.
+
+// journaled is implemented by backends that can keep a journal next to their trash.
+type journaled interface {
+	journalPath() string
+}
+
+// Only the most recent batches are kept
+const maxJournalEntries = 100
+
+type journalItem struct {
+	Location     string `json:"location"`
+	InTrashBox   string `json:"inTrashBox"`
+	DeletionDate string `json:"deletionDate"` // as recorded by the trash, see fi.deletionDate
+}
+
+// matches reports whether file is the item that was trashed.
+// Trash names are reused once an item leaves the trash, so the trash path alone is not enough.
+// The deletion date is compared as recorded, as the parsed time depends on the time zone.
+func (item journalItem) matches(file fi) bool {
+	return item.InTrashBox == file.inTrashBox && item.Location == file.location && item.DeletionDate == file.deletionDate
+}
+
+// matches reports whether file is one of the items of e.
+func (e journalEntry) matches(file fi) bool {
+	return slices.ContainsFunc(e.Items, func(item journalItem) bool { return item.matches(file) })
+}
+
+// inTrash returns the items of e that are still in the trash.
+func (e journalEntry) inTrash(files []fi) []journalItem {
+	var items []journalItem
+	for _, item := range e.Items {
+		if slices.ContainsFunc(files, item.matches) {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+type journalEntry struct {
+	Time  time.Time     `json:"time"`
+	Cwd   string        `json:"cwd"`
+	Argv  []string      `json:"argv"`
+	Items []journalItem `json:"items"`
+}
+
+func readJournal(path string) ([]journalEntry, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []journalEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 16*1024*1024)
+	for scanner.Scan() {
+		var e journalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			// Skip a line left half-written by an interrupted command
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+// writeJournal replaces the journal atomically with the last maxJournalEntries entries.
+func writeJournal(path string, entries []journalEntry) error {
+	if len(entries) > maxJournalEntries {
+		entries = entries[len(entries)-maxJournalEntries:]
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	enc := json.NewEncoder(tmp)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// recordBatch appends the files trashed by this command to the journal of b, if it keeps one.
+func recordBatch(b Backend, files []fi) error {
+	j, ok := b.(journaled)
+	if !ok || len(files) == 0 {
+		return nil
+	}
+
+	cwd, _ := os.Getwd()
+	entry := journalEntry{Time: time.Now(), Cwd: cwd, Argv: os.Args}
+	for _, file := range files {
+		entry.Items = append(entry.Items, journalItem{file.location, file.inTrashBox, file.deletionDate})
+	}
+
+	entries, err := readJournal(j.journalPath())
+	if err != nil {
+		return err
+	}
+	return writeJournal(j.journalPath(), append(entries, entry))
+}
+
+// undoBatch restores the files trashed by the n-th most recent recorded command (1 is the last one).
+// Once the undo is done, the command only keeps the files that could not be restored.
+// A command whose files have all left the trash since is reported and dropped from the journal,
+// rather than undoing another command in its place.
+func undoBatch(b Backend, n int, opts restoreOptions) error {
+	j, ok := b.(journaled)
+	if !ok {
+		return errors.New("--undo is not supported by this trash")
+	}
+
+	entries, err := readJournal(j.journalPath())
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		fmt.Println("Nothing to undo.")
+		return nil
+	}
+	if n < 1 || n > len(entries) {
+		return fmt.Errorf("there are only %d trash commands to undo", len(entries))
+	}
+	idx := len(entries) - n
+	entry := entries[idx]
+
+	files, err := b.List()
+	if err != nil {
+		return err
+	}
+	if len(entry.inTrash(files)) == 0 {
+		if err := writeJournal(j.journalPath(), slices.Delete(entries, idx, idx+1)); err != nil {
+			return err
+		}
+		return fmt.Errorf("the files trashed by %s are no longer in the trash, so it was dropped from the journal", shellJoin(entry.Argv))
+	}
+
+	fmt.Printf("Undo %s (in %s at %s)\n", shellJoin(entry.Argv), entry.Cwd, entry.Time.Format("2006-01-02 15:04:05"))
+	opts.batch = true
+	opts.assumeYes = true
+	undoErr := undelete(b, entry.matches, "the undone command", opts)
+
+	// Keep what is still in the trash, so that it can be undone later
+	if files, err = b.List(); err != nil {
+		return err
+	}
+	if entries[idx].Items = entry.inTrash(files); len(entries[idx].Items) == 0 {
+		entries = slices.Delete(entries, idx, idx+1)
+	}
+	if err := writeJournal(j.journalPath(), entries); err != nil {
+		return err
+	}
+	return undoErr
+}
+
+// shellJoin quotes argv for display as a shell command line.
+func shellJoin(argv []string) string {
+	quoted := make([]string, len(argv))
+	for i, arg := range argv {
+		if arg == "" || strings.ContainsAny(arg, " \t\n'\"\\$`*?[]{}()<>|&;#~") {
+			arg = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+		}
+		quoted[i] = arg
+	}
+	return strings.Join(quoted, " ")
+}
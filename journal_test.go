@@ -0,0 +1,116 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// trashBatch puts the named files of dir into m as one recorded command.
+// The files are removed as a real trash would, so that they can be restored.
+func trashBatch(t *testing.T, m *memTrash, dir string, names ...string) {
+	t.Helper()
+	var files []fi
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+		file, err := m.Put(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		os.Remove(path)
+		files = append(files, file)
+	}
+	if err := recordBatch(m, files); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// names returns the file names of the items in m.
+func (m *memTrash) names() []string {
+	var names []string
+	for _, file := range m.items {
+		names = append(names, file.filename)
+	}
+	return names
+}
+
+func TestUndoBatch(t *testing.T) {
+	dir := t.TempDir()
+	m := &memTrash{journal: filepath.Join(t.TempDir(), "go-trash.journal")}
+	trashBatch(t, m, dir, "a")
+	trashBatch(t, m, dir, "b", "c")
+
+	if err := undoBatch(m, 1, restoreOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.names(); !slices.Equal(got, []string{"a"}) {
+		t.Errorf("trash after undo = %v, want [a]", got)
+	}
+	entries, err := readJournal(m.journal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || len(entries[0].Items) != 1 || entries[0].Items[0].Location != filepath.Join(dir, "a") {
+		t.Errorf("journal after undo = %v, want only the command trashing a", entries)
+	}
+
+	if err := undoBatch(m, 2, restoreOptions{}); err == nil {
+		t.Error("undoBatch(2) with one command left succeeded")
+	}
+}
+
+// When the files of the requested command have left the trash, an older command must not be undone instead.
+func TestUndoBatchDoesNotFallThrough(t *testing.T) {
+	dir := t.TempDir()
+	m := &memTrash{journal: filepath.Join(t.TempDir(), "go-trash.journal")}
+	trashBatch(t, m, dir, "w")
+	trashBatch(t, m, dir, "tz1")
+	if err := m.Purge(m.items[1]); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := undoBatch(m, 1, restoreOptions{}); err == nil {
+		t.Error("undoBatch succeeded for a command whose files have left the trash")
+	}
+	if got := m.names(); !slices.Equal(got, []string{"w"}) {
+		t.Errorf("trash after undo = %v, want [w]", got)
+	}
+
+	// The gone command was dropped, so the next undo is the older one
+	if err := undoBatch(m, 1, restoreOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.names(); len(got) != 0 {
+		t.Errorf("trash after second undo = %v, want it empty", got)
+	}
+}
+
+func TestUndoBatchKeepsFailedItems(t *testing.T) {
+	dir := t.TempDir()
+	m := &memTrash{journal: filepath.Join(t.TempDir(), "go-trash.journal")}
+	trashBatch(t, m, dir, "a", "b")
+	m.failOn = map[string]bool{filepath.Join(dir, "b"): true}
+
+	err := undoBatch(m, 1, restoreOptions{})
+	if code := exitCode(err); code != exitPartial {
+		t.Errorf("exitCode(%v) = %d, want %d", err, code, exitPartial)
+	}
+	entries, err := readJournal(m.journal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || len(entries[0].Items) != 1 || entries[0].Items[0].Location != filepath.Join(dir, "b") {
+		t.Errorf("journal after undo = %v, want only b left", entries)
+	}
+}
+
+func TestUndoBatchWithEmptyJournal(t *testing.T) {
+	m := &memTrash{journal: filepath.Join(t.TempDir(), "go-trash.journal")}
+	if err := undoBatch(m, 1, restoreOptions{}); err != nil {
+		t.Errorf("undoBatch with an empty journal: %v", err)
+	}
+}
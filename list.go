@@ -0,0 +1,299 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// listItem is a trashed file as written by the machine-readable formats of -l.
+type listItem struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	Location     string    `json:"location"`
+	InTrashBox   string    `json:"inTrashBox"`
+	DeletionDate time.Time `json:"deletionDate"`
+	Size         int64     `json:"size"`
+	Type         string    `json:"type"`
+}
+
+func newListItem(file fi) listItem {
+	return listItem{
+		ID:         stableID(file),
+		Name:       file.filename,
+		Location:   file.location,
+		InTrashBox: file.inTrashBox,
+		// Whole seconds, so that it is marshaled as plain RFC 3339
+		DeletionDate: file.dateDeleted.Truncate(time.Second),
+		Size:         file.size,
+		Type:         file.kind,
+	}
+}
+
+// listOptions selects and orders the items shown by -l.
+type listOptions struct {
+	sortBy  string // name, size, date or location, empty for the trash order
+	reverse bool
+	filters []func(fi) bool
+}
+
+var sortKeys = map[string]func(a, b fi) int{
+	"name":     func(a, b fi) int { return strings.Compare(a.filename, b.filename) },
+	"size":     func(a, b fi) int { return cmpInt64(a.size, b.size) },
+	"date":     func(a, b fi) int { return a.dateDeleted.Compare(b.dateDeleted) },
+	"location": func(a, b fi) int { return strings.Compare(a.location, b.location) },
+}
+
+func cmpInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// newListOptions builds the listOptions from the --sort, --reverse, --since, --until,
+// --min-size, --under and --type values. Empty values do not filter.
+func newListOptions(sortBy string, reverse bool, since, until, minSize, under, kind string, now time.Time) (listOptions, error) {
+	opts := listOptions{sortBy: sortBy, reverse: reverse}
+	if _, ok := sortKeys[sortBy]; sortBy != "" && !ok {
+		return opts, fmt.Errorf("invalid sort key %q (name, size, date or location)", sortBy)
+	}
+
+	if since != "" || until != "" {
+		var from, to time.Time
+		var err error
+		if since != "" {
+			if from, err = parseTime(since, now); err != nil {
+				return opts, err
+			}
+		}
+		if until != "" {
+			if to, err = parseTime(until, now); err != nil {
+				return opts, err
+			}
+		}
+		opts.filters = append(opts.filters, deletedBetween(from, to))
+	}
+	if minSize != "" {
+		min, err := parseSize(minSize)
+		if err != nil {
+			return opts, err
+		}
+		opts.filters = append(opts.filters, func(file fi) bool { return file.size >= min })
+	}
+	if under != "" {
+		opts.filters = append(opts.filters, func(file fi) bool { return isUnder(file.location, under) })
+	}
+	if kind != "" {
+		if kind != kindFile && kind != kindDir && kind != kindSymlink {
+			return opts, fmt.Errorf("invalid type %q (file, dir or symlink)", kind)
+		}
+		opts.filters = append(opts.filters, func(file fi) bool { return file.kind == kind })
+	}
+	return opts, nil
+}
+
+// listFiles returns the trashed files selected by opts, in the requested order.
+func listFiles(b Backend, opts listOptions) ([]fi, error) {
+	files, err := b.List()
+	if err != nil {
+		return nil, err
+	}
+
+	match := allOf(opts.filters...)
+	files = slices.DeleteFunc(files, func(file fi) bool { return !match(file) })
+	if cmp, ok := sortKeys[opts.sortBy]; ok {
+		slices.SortStableFunc(files, cmp)
+	}
+	if opts.reverse {
+		slices.Reverse(files)
+	}
+	return files, nil
+}
+
+var listFormats = []string{"json", "ndjson", "csv", "tsv"}
+
+func checkListFormat(format string) error {
+	for _, f := range listFormats {
+		if f == format {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid format %q (json, ndjson, csv or tsv)", format)
+}
+
+// writeItems writes files to w in one of listFormats.
+func writeItems(w io.Writer, files []fi, format string) error {
+	items := make([]listItem, 0, len(files))
+	for _, file := range files {
+		items = append(items, newListItem(file))
+	}
+
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(items)
+	case "ndjson":
+		enc := json.NewEncoder(w)
+		for _, item := range items {
+			if err := enc.Encode(item); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "csv", "tsv":
+		cw := csv.NewWriter(w)
+		if format == "tsv" {
+			cw.Comma = '\t'
+		}
+		cw.Write([]string{"id", "name", "location", "inTrashBox", "deletionDate", "size", "type"})
+		for _, item := range items {
+			cw.Write([]string{
+				item.ID,
+				item.Name,
+				item.Location,
+				item.InTrashBox,
+				item.DeletionDate.Format(time.RFC3339),
+				strconv.FormatInt(item.Size, 10),
+				item.Type,
+			})
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+	return checkListFormat(format)
+}
+
+// How sizes are shown by -l and the TUI
+type sizeUnits uint
+
+const (
+	unitsIEC   sizeUnits = iota // powers of 1024: KiB, MiB, ...
+	unitsSI                     // powers of 1000: kB, MB, ...
+	unitsBytes                  // plain byte counts
+)
+
+// displayUnits is set by --si and --bytes.
+var displayUnits = unitsIEC
+
+// formatSize formats n bytes in displayUnits.
+func formatSize(n int64) string {
+	switch displayUnits {
+	case unitsSI:
+		return scaleSize(n, 1000, "kMGTPE", "B")
+	case unitsBytes:
+		return strconv.FormatInt(n, 10)
+	}
+	return humanSize(n)
+}
+
+// humanSize formats n bytes with IEC units, e.g. "1.5 KiB".
+func humanSize(n int64) string {
+	return scaleSize(n, 1024, "KMGTPE", "iB")
+}
+
+func scaleSize(n int64, unit int64, prefixes string, suffix string) string {
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := unit, 0
+	for m := n / unit; m >= unit && exp < len(prefixes)-1; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %c%s", float64(n)/float64(div), prefixes[exp], suffix)
+}
+
+// totals summarizes files for the footer of -l and the TUI:
+// the number of items, their total size and the oldest one.
+func totals(files []fi) string {
+	size := formatSize(totalSize(files))
+	if displayUnits == unitsBytes {
+		size += " bytes"
+	}
+	summary := fmt.Sprintf("%d items, %s", len(files), size)
+
+	var oldest *fi
+	for i := range files {
+		if !files[i].dateDeleted.IsZero() && (oldest == nil || files[i].dateDeleted.Before(oldest.dateDeleted)) {
+			oldest = &files[i]
+		}
+	}
+	if oldest != nil {
+		summary += fmt.Sprintf(", oldest %s (deleted %s)", oldest.filename, relativeTime(oldest.dateDeleted))
+	}
+	return summary
+}
+
+// relativeTime formats t as the time elapsed since then, e.g. "3d ago".
+func relativeTime(t time.Time) string {
+	if t.IsZero() {
+		return "unknown"
+	}
+	d := time.Since(t)
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	case d < 14*24*time.Hour:
+		return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+	}
+	return fmt.Sprintf("%dw ago", int(d.Hours()/24/7))
+}
+
+// shortPath replaces the home directory at the start of path with "~".
+func shortPath(path string) string {
+	home, err := os.UserHomeDir()
+	if err != nil || home == "" {
+		return path
+	}
+	if path == home {
+		return "~"
+	}
+	if rest, ok := strings.CutPrefix(path, home+string(filepath.Separator)); ok {
+		return filepath.Join("~", rest)
+	}
+	return path
+}
+
+var templateFuncs = template.FuncMap{
+	"human": humanSize,
+	"ago":   relativeTime,
+	"short": shortPath,
+}
+
+// parseListTemplate parses a --template. As the template is usually given in single quotes
+// on the command line, the escapes \t, \n and \\ are interpreted.
+func parseListTemplate(text string) (*template.Template, error) {
+	text = strings.NewReplacer(`\t`, "\t", `\n`, "\n", `\\`, `\`).Replace(text)
+	if !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	return template.New("list").Funcs(templateFuncs).Parse(text)
+}
+
+// executeTemplate renders each of files with tmpl.
+// The fields are those of listItem: .ID, .Name, .Location, .InTrashBox, .DeletionDate, .Size and .Type.
+func executeTemplate(w io.Writer, files []fi, tmpl *template.Template) error {
+	for _, file := range files {
+		if err := tmpl.Execute(w, newListItem(file)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteItems(t *testing.T) {
+	deleted := time.Date(2024, 1, 2, 3, 4, 5, 0, time.FixedZone("JST", 9*60*60))
+	files := []fi{
+		{filename: "a.txt", location: "/home/user/a.txt", inTrashBox: "/trash/files/a.txt", dateDeleted: deleted, deletionDate: "2024-01-02T03:04:05", size: 7, kind: kindFile},
+		{filename: "odd, \"name\"\n\tx", location: "/home/user/odd, \"name\"\n\tx", inTrashBox: "/trash/files/odd", dateDeleted: deleted.Add(time.Hour), size: 4096, kind: kindDir},
+	}
+	want := []listItem{newListItem(files[0]), newListItem(files[1])}
+
+	check := func(t *testing.T, got []listItem) {
+		t.Helper()
+		if len(got) != len(want) {
+			t.Fatalf("%d items, want %d", len(got), len(want))
+		}
+		for i := range want {
+			if !got[i].DeletionDate.Equal(want[i].DeletionDate) {
+				t.Errorf("item %d deletionDate = %v, want %v", i, got[i].DeletionDate, want[i].DeletionDate)
+			}
+			got[i].DeletionDate = want[i].DeletionDate
+			if got[i] != want[i] {
+				t.Errorf("item %d = %+v, want %+v", i, got[i], want[i])
+			}
+		}
+	}
+
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := writeItems(&buf, files, "json"); err != nil {
+			t.Fatal(err)
+		}
+		var got []listItem
+		if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		check(t, got)
+		if !strings.Contains(buf.String(), `"deletionDate": "2024-01-02T03:04:05+09:00"`) {
+			t.Errorf("deletionDate is not RFC 3339:\n%s", buf.String())
+		}
+	})
+
+	t.Run("ndjson", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := writeItems(&buf, files, "ndjson"); err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+		var got []listItem
+		for _, line := range lines {
+			var item listItem
+			if err := json.Unmarshal([]byte(line), &item); err != nil {
+				t.Fatalf("line %q: %v", line, err)
+			}
+			got = append(got, item)
+		}
+		check(t, got)
+	})
+
+	for _, format := range []string{"csv", "tsv"} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writeItems(&buf, files, format); err != nil {
+				t.Fatal(err)
+			}
+			r := csv.NewReader(&buf)
+			if format == "tsv" {
+				r.Comma = '\t'
+			}
+			records, err := r.ReadAll()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if header := strings.Join(records[0], ","); header != "id,name,location,inTrashBox,deletionDate,size,type" {
+				t.Errorf("header = %q", header)
+			}
+			var got []listItem
+			for _, rec := range records[1:] {
+				date, err := time.Parse(time.RFC3339, rec[4])
+				if err != nil {
+					t.Fatal(err)
+				}
+				size, err := strconv.ParseInt(rec[5], 10, 64)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, listItem{rec[0], rec[1], rec[2], rec[3], date, size, rec[6]})
+			}
+			check(t, got)
+		})
+	}
+}
+
+func TestWriteItemsEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeItems(&buf, nil, "json"); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "[]\n" {
+		t.Errorf("json of no items = %q, want []", buf.String())
+	}
+	if err := writeItems(&buf, nil, "yaml"); err == nil {
+		t.Error("writeItems accepted the format yaml")
+	}
+}
@@ -1,10 +1,10 @@
 package main
 
 import (
-	"bufio"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -17,15 +17,6 @@ import (
 	"github.com/pborman/getopt/v2"
 )
 
-type fi struct {
-	id          string // I know bad. but the type use in []table.Row{} is string
-	filename    string
-	location    string
-	inTrashBox  string
-	dateDeleted time.Time
-	size        int64
-}
-
 var columns = []table.Column{
 	{Title: "#", Width: 5},
 	{Title: "Name", Width: 20},
@@ -51,15 +42,20 @@ type tableModel struct {
 	isfilter  bool
 	allRows   []table.Row
 	trashList []fi
+	backend   Backend
+	conflict  *fi // item waiting for a decision because its location already exists
+	noParent  *fi // item waiting for confirmation to recreate the missing directories above its location
+	status    string
 }
 
 type RowsUpdatedMsg struct {
-	Rows []table.Row
+	Rows      []table.Row
+	TrashList []fi
 }
 
 var numTableRows int = 20
 
-func newTableModel(rows []table.Row, trashList []fi) tableModel {
+func newTableModel(rows []table.Row, trashList []fi, backend Backend) tableModel {
 	// Create the input
 	ti := textinput.New()
 	ti.Placeholder = "…"
@@ -91,6 +87,7 @@ func newTableModel(rows []table.Row, trashList []fi) tableModel {
 		textInput: ti,
 		allRows:   rows,
 		trashList: trashList,
+		backend:   backend,
 	}
 }
 
@@ -102,6 +99,37 @@ func (m tableModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
 		switch {
+		case m.conflict != nil:
+			// Waiting for [s]kip, [r]ename or [o]verwrite
+			f := *m.conflict
+			m.conflict = nil
+			switch msg.String() {
+			case "r":
+				return m.restore(f, restoredName(f.location))
+			case "o":
+				if _, err := m.backend.Put(f.location); err != nil {
+					m.status = err.Error()
+					return m, nil
+				}
+				// The overwritten file is in the trash now, so list it again
+				restored, _ := m.restore(f, f.location)
+				return restored.(tableModel).reload()
+			}
+			return m, nil
+		case m.noParent != nil:
+			// Waiting for [y]es or [n]o, as createParents asks on the command line
+			f := *m.noParent
+			m.noParent = nil
+			if msg.String() != "y" {
+				return m, nil
+			}
+			for _, dir := range missingParents(f.location) {
+				if err := mkdirParent(dir); err != nil {
+					m.status = err.Error()
+					return m, nil
+				}
+			}
+			return m.restore(f, f.location)
 		case msg.String() == "ctrl+c" || msg.String() == "esc":
 			// Cancel filter
 			if m.isfilter {
@@ -116,28 +144,19 @@ func (m tableModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				// Get ID
 				id := m.table.Rows()[cursor][0]
 
-				// remove fi from trashList
-				for i, f := range m.trashList {
+				for _, f := range m.trashList {
 					if f.id == id {
-						Undelete(f.inTrashBox, f.location)
-						m.trashList = append(m.trashList[:i], m.trashList[i+1:]...)
-						break
-					}
-				}
-				// Remove from allRows
-				for i := range m.allRows {
-					if m.allRows[i][0] == id {
-						m.allRows = append(m.allRows[:i], m.allRows[i+1:]...)
-						break
+						if len(missingParents(f.location)) > 0 {
+							m.noParent = &f
+							return m, nil
+						}
+						if exists(f.location) {
+							m.conflict = &f
+							return m, nil
+						}
+						return m.restore(f, f.location)
 					}
 				}
-				// update table view
-				m.table.SetRows(m.allRows)
-
-				// update rows to mainModel
-				return m, func() tea.Msg {
-					return RowsUpdatedMsg{Rows: m.allRows}
-				}
 			}
 		case msg.String() == "/":
 			m.isfilter = true
@@ -171,6 +190,52 @@ func (m tableModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// restore undeletes f to dst and removes it from the table.
+func (m tableModel) restore(f fi, dst string) (tea.Model, tea.Cmd) {
+	if err := m.backend.Restore(f, dst); err != nil {
+		m.status = err.Error()
+		return m, nil
+	}
+	m.status = "UnDelete " + f.filename + " → " + dst
+
+	// remove fi from trashList
+	for i := range m.trashList {
+		if m.trashList[i].id == f.id {
+			m.trashList = append(m.trashList[:i], m.trashList[i+1:]...)
+			break
+		}
+	}
+	// Remove from allRows
+	for i := range m.allRows {
+		if m.allRows[i][0] == f.id {
+			m.allRows = append(m.allRows[:i], m.allRows[i+1:]...)
+			break
+		}
+	}
+	// update table view
+	m.table.SetRows(m.allRows)
+
+	// update rows to mainModel
+	return m, func() tea.Msg {
+		return RowsUpdatedMsg{Rows: m.allRows, TrashList: m.trashList}
+	}
+}
+
+// reload lists the trash again, for when the TUI has moved something into it.
+func (m tableModel) reload() (tea.Model, tea.Cmd) {
+	trashList, err := m.backend.List()
+	if err != nil {
+		m.status = err.Error()
+		return m, nil
+	}
+	m.trashList, m.allRows = newRows(trashList)
+	m.table.SetRows(m.allRows)
+
+	return m, func() tea.Msg {
+		return RowsUpdatedMsg{Rows: m.allRows, TrashList: m.trashList}
+	}
+}
+
 func (m tableModel) View() string {
 	var sb strings.Builder
 	// Header
@@ -186,6 +251,18 @@ func (m tableModel) View() string {
 
 	// Footer
 	sb.WriteString("\n\n")
+	sb.WriteString("Total: " + totals(m.trashList) + "\n")
+	if m.conflict != nil {
+		sb.WriteString(m.conflict.location + " already exists. [s]kip [r]ename [o]verwrite\n")
+		return sb.String()
+	}
+	if m.noParent != nil {
+		sb.WriteString(filepath.Dir(m.noParent.location) + " does not exist. Create it? [y/N]\n")
+		return sb.String()
+	}
+	if m.status != "" {
+		sb.WriteString(m.status + "\n")
+	}
 	if m.isfilter {
 		sb.WriteString("[Enter]: apply filter  [Esc]:cancel filter\n")
 	} else {
@@ -290,7 +367,7 @@ func (m detailModel) View() string {
 
 	// Body
 	for i, v := range m.row {
-		sb.WriteString(fmt.Sprintf("%-18s: %s\n", columns[i].Title, v))
+		sb.WriteString(fmt.Sprintf("%-18s: %s\n", columns[i].Title, strings.TrimSpace(v)))
 	}
 	// file contents
 	if m.showViewer {
@@ -310,6 +387,7 @@ type mainModel struct {
 	rows      []table.Row
 	trashList []fi
 	textInput textinput.Model
+	backend   Backend
 }
 
 func (m mainModel) Init() tea.Cmd {
@@ -320,11 +398,12 @@ func (m mainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case RowsUpdatedMsg:
 		m.rows = msg.Rows
+		m.trashList = msg.TrashList
 		return m, nil
 
 	case changeViewMsg:
 		if msg.toView == tableView {
-			tm := newTableModel(m.rows, m.trashList)
+			tm := newTableModel(m.rows, m.trashList, m.backend)
 			m.viewstate = tableView
 			m.sub = tm
 			return m, tm.Init()
@@ -361,21 +440,29 @@ func filterRows(rows []table.Row, keyword string) []table.Row {
 	return filtered
 }
 
-func initialModel() mainModel {
-	trashList, err := GetTrashBoxItems()
-	if err != nil {
-		fmt.Println("go-trash: ", err)
-		os.Exit(1)
-	}
-
+// newRows numbers the items of trashList and returns them with their table rows.
+func newRows(trashList []fi) ([]fi, []table.Row) {
 	var allRows = []table.Row{}
 	for i, tf := range trashList {
 		// add ID
 		tf.id = strconv.Itoa(i + 1)
 		trashList[i] = tf
-		row := []string{tf.id, tf.filename, strconv.FormatInt(tf.size, 10), tf.dateDeleted.Format(time.RFC3339), tf.location}
+		// Right-align the size in its column
+		size := fmt.Sprintf("%*s", columns[2].Width, formatSize(tf.size))
+		row := []string{tf.id, tf.filename, size, tf.dateDeleted.Format(time.RFC3339), tf.location}
 		allRows = append(allRows, row)
 	}
+	return trashList, allRows
+}
+
+func initialModel(backend Backend) mainModel {
+	trashList, err := backend.List()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "go-trash: ", err)
+		os.Exit(exitFailure)
+	}
+
+	trashList, allRows := newRows(trashList)
 
 	// Create the input
 	ti := textinput.New()
@@ -403,14 +490,126 @@ func initialModel() mainModel {
 		Bold(false)
 	t.SetStyles(s)
 
-	start := newTableModel(allRows, trashList)
+	start := newTableModel(allRows, trashList, backend)
 	return mainModel{
 		viewstate: tableView,
 		sub:       start,
 		rows:      allRows,
 		textInput: ti,
 		trashList: trashList,
+		backend:   backend,
+	}
+}
+
+// parseCommandLine parses the options anywhere on the command line, as rm does,
+// and returns the parameters. Everything after "--" is a parameter.
+// fn is called for each option in the order they are given.
+func parseCommandLine(fn func(getopt.Option) bool) ([]string, error) {
+	var params []string
+	args := os.Args
+	for {
+		if err := getopt.CommandLine.Getopt(args, fn); err != nil {
+			return nil, err
+		}
+		rest := getopt.Args()
+		if getopt.CommandLine.State() == getopt.DashDash || len(rest) == 0 {
+			return append(params, rest...), nil
+		}
+		params = append(params, rest[0])
+		args = append([]string{args[0]}, rest[1:]...)
+	}
+}
+
+// modes are the options selecting what go-trash does. Options of the same mode can be combined.
+// Without any of them, go-trash trashes its parameters, or runs the TUI when there are none.
+var modes = [][]string{
+	{"-u", "--restore"},
+	{"--undo"},
+	{"-l"},
+	{"--empty"},
+	{"--purge"},
+	{"--older-than", "--max-size"},
+	{"-t"},
+}
+
+// Pseudo mode of the options that apply when trashing files
+const trashMode = "trash"
+
+// modeOptions are the modes each option applies to. Other options apply to every mode.
+var modeOptions = map[string][]string{
+	"--glob":            {"-u"},
+	"--regex":           {"-u"},
+	"--path":            {"-u"},
+	"--id":              {"-u"},
+	"-o":                {"-u", "--restore"},
+	"--conflict":        {"-u", "--restore", "--undo"},
+	"--parents":         {"-u", "--restore", "--undo"},
+	"--since":           {"-u", "--restore", "-l"},
+	"--between":         {"-u", "--restore"},
+	"--yes":             {"-u", "--restore", "--empty", "--purge"},
+	"--all-volumes":     {"--empty"},
+	"--keep-newer-than": {"--older-than", "--max-size"},
+	"--format":          {"-l"},
+	"--template":        {"-l"},
+	"--sort":            {"-l"},
+	"--reverse":         {"-l"},
+	"--until":           {"-l"},
+	"--min-size":        {"-l"},
+	"--under":           {"-l"},
+	"--type":            {"-l"},
+	"--bytes":           {"-l", "-t"},
+	"--si":              {"-l", "-t"},
+	"-r":                {trashMode},
+	"-R":                {trashMode},
+	"-d":                {trashMode},
+	"-f":                {trashMode},
+	"-i":                {trashMode},
+	"-I":                {trashMode},
+	"-v":                {trashMode},
+}
+
+// checkOptions reports a usage error when the options seen select more than one mode,
+// when an option does not apply to the selected mode, or when the mode takes no parameters.
+func checkOptions(seen []string, args []string) error {
+	used := map[string]bool{}
+	for _, name := range seen {
+		used[name] = true
+	}
+
+	var mode []string
+	for _, m := range modes {
+		for _, name := range m {
+			if !used[name] {
+				continue
+			}
+			if len(mode) > 0 && !slices.Contains(m, mode[0]) {
+				return fmt.Errorf("%s and %s cannot be used together", mode[0], name)
+			}
+			mode = append(mode, name)
+		}
+	}
+	if len(mode) == 0 {
+		mode = []string{trashMode}
+		if len(args) == 0 && !slices.ContainsFunc(seen, func(name string) bool { return slices.Contains(modeOptions[name], trashMode) }) {
+			mode = []string{"-t"}
+		}
+	}
+
+	for _, name := range seen {
+		applies, ok := modeOptions[name]
+		if ok && !slices.ContainsFunc(applies, func(m string) bool { return slices.Contains(mode, m) }) {
+			if applies[0] == trashMode {
+				return fmt.Errorf("%s only applies when trashing files", name)
+			}
+			return fmt.Errorf("%s only applies with %s", name, strings.Join(applies, " or "))
+		}
+	}
+
+	takesParams := slices.Contains(mode, trashMode) || slices.Contains(mode, "--purge") || slices.Contains(mode, "--undo") || used["--between"]
+	if len(args) > 0 && !takesParams {
+		return fmt.Errorf("unexpected parameter %q", args[0])
 	}
+	return nil
 }
 
 func main() {
@@ -420,86 +619,337 @@ func main() {
 		undeleteFile = ""
 		outputPath   = ""
 		isTuiMode    = false
+		trashDir     = os.Getenv("GO_TRASH_DIR")
+		isEmpty      = false
+		allVolumes   = false
+		assumeYes    = false
+		olderThan    = ""
+		maxSize      = ""
+		keepNewer    = ""
+		isPurge      = false
+		conflict     = string(conflictAsk)
+		parents      = false
+		useGlob      = false
+		useRegex     = false
+		usePath      = false
+		useID        = false
+		isRestore    = false
+		since        = ""
+		between      = ""
+		isUndo       = false
+		listFormat   = ""
+		listTemplate = ""
+		sortBy       = ""
+		reverse      = false
+		until        = ""
+		minSize      = ""
+		under        = ""
+		kind         = ""
+		showBytes    = false
+		showSI       = false
+		rmOpts       = rmOptions{}
+		isRm         = false
+		// Set by -i and -I, but rmOpts.prompt is set from the parse callback, where the last of -f, -i and -I wins
+		isPromptEach = false
+		isPromptOnce = false
 	)
 
 	getopt.Flag(&isList, 'l', "List trashed files")
+	getopt.FlagLong(&listFormat, "format", 0, "With -l, print the list as json, ndjson, csv or tsv", "Format").SetGroup("format")
+	getopt.FlagLong(&listTemplate, "template", 0, "With -l, print each item with a Go template (e.g. '{{.Name}}\\t{{.Size | human}}\\t{{.Location}}')", "Template").SetGroup("format")
+	getopt.FlagLong(&sortBy, "sort", 0, "With -l, sort by name, size, date or location", "Key")
+	getopt.FlagLong(&reverse, "reverse", 0, "With -l, reverse the order")
+	getopt.FlagLong(&until, "until", 0, "With -l, only list the files trashed until Time", "Time")
+	getopt.FlagLong(&minSize, "min-size", 0, "With -l, only list the files of at least Size", "Size")
+	getopt.FlagLong(&under, "under", 0, "With -l, only list the files whose original location is in Dir", "Dir")
+	getopt.FlagLong(&kind, "type", 0, "With -l, only list the items of Type: file, dir or symlink", "Type")
+	getopt.FlagLong(&showBytes, "bytes", 0, "With -l and the TUI, show sizes in bytes").SetGroup("units")
+	getopt.FlagLong(&showSI, "si", 0, "With -l and the TUI, show sizes in powers of 1000 (kB, MB, ...) instead of 1024 (KiB, MiB, ...)").SetGroup("units")
+	getopt.Flag(&rmOpts.recursive, 'r', "Trash directories and their contents")
+	getopt.Flag(&rmOpts.recursive, 'R', "Same as -r")
+	getopt.Flag(&rmOpts.emptyDirs, 'd', "Trash empty directories")
+	getopt.Flag(&rmOpts.force, 'f', "Ignore nonexistent files and never prompt")
+	getopt.Flag(&isPromptEach, 'i', "Prompt before every file")
+	getopt.Flag(&isPromptOnce, 'I', "Prompt once before trashing more than three files, or when trashing recursively")
+	getopt.Flag(&rmOpts.verbose, 'v', "Explain what is being done")
 	getopt.Flag(&isHelp, 'h', "Show help")
-	getopt.Flag(&undeleteFile, 'u', "Restore files to original location", "File")
-	getopt.Flag(&outputPath, 'o', "Output file to location", "File")
+	getopt.Flag(&undeleteFile, 'u', "Restore the files named File to their original location", "File")
+	getopt.Flag(&outputPath, 'o', "With -u, restore to File instead of the original location (a directory to restore into)", "File")
+	getopt.FlagLong(&isRestore, "restore", 0, "Restore the files selected by --since or --between")
+	getopt.FlagLong(&since, "since", 0, "With --restore or -l, select the files trashed since Time (e.g. 10m, 2024-01-02 15:04, now)", "Time")
+	getopt.FlagLong(&between, "between", 0, "With --restore, select the files trashed between two times (T1,T2)", "Times")
+	getopt.FlagLong(&isUndo, "undo", 0, "Restore the files trashed by the last command, or by the N-th last one given as parameter")
+	getopt.FlagLong(&conflict, "conflict", 0, "With -u, what to do when the destination exists: ask, skip, rename or overwrite", "Policy")
+	getopt.FlagLong(&parents, "parents", 0, "With -u, recreate missing parent directories without asking")
+	getopt.FlagLong(&useGlob, "glob", 0, "With -u, File is a glob pattern on the file name").SetGroup("match")
+	getopt.FlagLong(&useRegex, "regex", 0, "With -u, File is a regular expression on the file name").SetGroup("match")
+	getopt.FlagLong(&usePath, "path", 0, "With -u, File is the original location or a directory containing it").SetGroup("match")
+	getopt.FlagLong(&useID, "id", 0, "With -u, File is an item ID as shown by -l").SetGroup("match")
 	getopt.Flag(&isTuiMode, 't', "Run TUI mode")
-	getopt.Parse()
-	args := getopt.Args()
+	getopt.FlagLong(&trashDir, "trash-dir", 0, "Use Dir as the trash instead of the home trash (default $GO_TRASH_DIR)", "Dir")
+	getopt.FlagLong(&isEmpty, "empty", 0, "Permanently delete everything in the trash")
+	getopt.FlagLong(&allVolumes, "all-volumes", 0, "With --empty, also empty the trash of every mounted volume")
+	getopt.FlagLong(&assumeYes, "yes", 0, "Do not ask for confirmation")
+	getopt.FlagLong(&isPurge, "purge", 0, "Permanently delete the trashed items given as parameters (IDs, glob patterns or original paths)")
+	getopt.FlagLong(&olderThan, "older-than", 0, "Permanently delete items trashed more than Age ago (e.g. 12h, 30d, 2w)", "Age")
+	getopt.FlagLong(&maxSize, "max-size", 0, "Permanently delete the oldest items until the trash fits in Size (e.g. 500M, 20G)", "Size")
+	getopt.FlagLong(&keepNewer, "keep-newer-than", 0, "With --older-than or --max-size, never delete items trashed less than Age ago", "Age")
+	// The last of -f, -i and -I wins, as with rm
+	args, err := parseCommandLine(func(opt getopt.Option) bool {
+		switch opt.ShortName() {
+		case "f":
+			rmOpts.force, rmOpts.prompt = true, promptNever
+		case "i":
+			rmOpts.force, rmOpts.prompt = false, promptAlways
+		case "I":
+			rmOpts.force, rmOpts.prompt = false, promptOnce
+		}
+		switch opt.ShortName() {
+		case "r", "R", "d", "f", "i", "I", "v":
+			isRm = true
+		}
+		return true
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		getopt.Usage()
+		os.Exit(exitUsage)
+	}
 
-	if len(undeleteFile) != 0 {
-		trashfiles, err := GetTrashBoxItems()
-		if err != nil {
-			fmt.Println("go-trash: ", err)
-			os.Exit(1)
+	if isHelp {
+		getopt.Usage()
+		os.Exit(exitOK)
+	}
+
+	var seen []string
+	getopt.Visit(func(opt getopt.Option) {
+		if opt.LongName() != "" {
+			seen = append(seen, "--"+opt.LongName())
+		} else {
+			seen = append(seen, "-"+opt.ShortName())
 		}
+	})
+	if err := checkOptions(seen, args); err != nil {
+		fmt.Fprintln(os.Stderr, "go-trash: ", err)
+		os.Exit(exitUsage)
+	}
 
-		var udFileList []fi
-		for _, file := range trashfiles {
-			if strings.Contains(file.filename, undeleteFile) {
-				udFileList = append(udFileList, file)
+	switch {
+	case showBytes:
+		displayUnits = unitsBytes
+	case showSI:
+		displayUnits = unitsSI
+	}
+
+	backend, err := newBackend(trashDir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "go-trash: ", err)
+		os.Exit(exitFailure)
+	}
+
+	if isUndo {
+		policy, err := parseConflictPolicy(conflict)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "go-trash: ", err)
+			os.Exit(exitUsage)
+		}
+		n := 1
+		if len(args) > 0 {
+			n, err = strconv.Atoi(args[0])
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "go-trash:  --undo takes the number of the command to undo")
+				os.Exit(exitUsage)
 			}
 		}
+		err = undoBatch(backend, n, restoreOptions{conflict: policy, parents: parents})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "go-trash: ", err)
+			os.Exit(exitCode(err))
+		}
+		os.Exit(exitOK)
+	}
+
+	if len(undeleteFile) != 0 || isRestore {
+		policy, err := parseConflictPolicy(conflict)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "go-trash: ", err)
+			os.Exit(exitUsage)
+		}
 
-		if len(udFileList) > 1 {
-			fmt.Printf("Found %d files that matched.\n\n", len(udFileList))
-			for _, file := range udFileList {
-				fmt.Printf("Filename: %s\n", file.filename)
-				fmt.Printf("Location: %s\n\n", file.location)
+		var filters []func(fi) bool
+		var what []string
+		if len(undeleteFile) != 0 {
+			mode := matchName
+			switch {
+			case useGlob:
+				mode = matchGlob
+			case useRegex:
+				mode = matchRegex
+			case usePath:
+				mode = matchPath
+			case useID:
+				mode = matchID
 			}
-			fmt.Printf("Do you want to undelete them? [Y/n]: ")
-			scanner := bufio.NewScanner(os.Stdin)
-			scanner.Scan()
-			if scanner.Text() != "Y" {
-				os.Exit(0)
+			match, err := newMatcher(undeleteFile, mode)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "go-trash: ", err)
+				os.Exit(exitUsage)
 			}
+			filters = append(filters, match)
+			what = append(what, undeleteFile)
 		}
 
-		for _, file := range udFileList {
-			err := Undelete(file.inTrashBox, file.location)
+		// Select by deletion time
+		batch := false
+		now := time.Now()
+		if len(since) != 0 {
+			from, err := parseTime(since, now)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "go-trash: ", err)
+				os.Exit(exitUsage)
+			}
+			filters = append(filters, deletedBetween(from, time.Time{}))
+			what = append(what, "deleted since "+from.Format(time.RFC3339))
+			batch = true
+		}
+		if len(between) != 0 {
+			from, to, err := parseBetween(between, args, now)
 			if err != nil {
-				fmt.Println("go-trash: ", err)
-				os.Exit(1)
+				fmt.Fprintln(os.Stderr, "go-trash: ", err)
+				os.Exit(exitUsage)
 			}
-			fmt.Printf("UnDelete %s → %s\n", file.filename, file.location)
+			filters = append(filters, deletedBetween(from, to))
+			what = append(what, "deleted between "+from.Format(time.RFC3339)+" and "+to.Format(time.RFC3339))
+			batch = true
+		}
+		if len(filters) == 0 {
+			fmt.Fprintln(os.Stderr, "go-trash:  --restore needs -u, --since or --between")
+			os.Exit(exitUsage)
+		}
+
+		err = undelete(backend, allOf(filters...), strings.Join(what, ", "), restoreOptions{outputPath, policy, parents, batch, assumeYes})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "go-trash: ", err)
+			os.Exit(exitCode(err))
+		}
+		os.Exit(exitOK)
+	}
+
+	var listOpts listOptions
+	if isList {
+		listOpts, err = newListOptions(sortBy, reverse, since, until, minSize, under, kind, time.Now())
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "go-trash: ", err)
+			os.Exit(exitUsage)
 		}
+	}
 
-		os.Exit(0)
+	if isList && len(listFormat) != 0 {
+		if err := checkListFormat(listFormat); err != nil {
+			fmt.Fprintln(os.Stderr, "go-trash: ", err)
+			os.Exit(exitUsage)
+		}
+		files, err := listFiles(backend, listOpts)
+		if err == nil {
+			err = writeItems(os.Stdout, files, listFormat)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "go-trash: ", err)
+			os.Exit(exitCode(err))
+		}
+		os.Exit(exitOK)
+	}
+
+	if isList && len(listTemplate) != 0 {
+		tmpl, err := parseListTemplate(listTemplate)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "go-trash: ", err)
+			os.Exit(exitUsage)
+		}
+		files, err := listFiles(backend, listOpts)
+		if err == nil {
+			err = executeTemplate(os.Stdout, files, tmpl)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "go-trash: ", err)
+			os.Exit(exitCode(err))
+		}
+		os.Exit(exitOK)
 	}
 
 	if isList {
 		fmt.Println("")
 		fmt.Println("🗑️ TrashBox 🗑️")
-		err := PrintTrashBoxItems()
+		files, err := listFiles(backend, listOpts)
 		if err != nil {
-			fmt.Println("go-trash: ", err)
-			os.Exit(1)
+			fmt.Fprintln(os.Stderr, "go-trash: ", err)
+			os.Exit(exitCode(err))
 		}
-		os.Exit(0)
+		PrintTrashBoxItems(files)
+		os.Exit(exitOK)
 	}
 
-	if isHelp {
-		getopt.Usage()
-		os.Exit(1)
+	if isEmpty {
+		err := emptyTrash(backend, allVolumes, assumeYes)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "go-trash: ", err)
+			os.Exit(exitCode(err))
+		}
+		os.Exit(exitOK)
+	}
+
+	if isPurge {
+		if len(args) == 0 {
+			fmt.Fprintln(os.Stderr, "go-trash:  --purge needs the items to delete")
+			os.Exit(exitUsage)
+		}
+		err := purgeSelected(backend, args, assumeYes)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "go-trash: ", err)
+			os.Exit(exitCode(err))
+		}
+		os.Exit(exitOK)
+	}
+
+	if len(olderThan) != 0 || len(maxSize) != 0 {
+		policy, err := newPurgePolicy(olderThan, maxSize, keepNewer)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "go-trash: ", err)
+			os.Exit(exitUsage)
+		}
+		err = purgeTrash(backend, policy)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "go-trash: ", err)
+			os.Exit(exitCode(err))
+		}
+		os.Exit(exitOK)
+	}
+
+	if isRm && len(args) == 0 {
+		// Like rm -f, succeed silently without operands
+		if rmOpts.force {
+			os.Exit(exitOK)
+		}
+		fmt.Fprintln(os.Stderr, "go-trash:  missing operand")
+		os.Exit(exitUsage)
 	}
 
 	if isTuiMode || len(args) == 0 {
-		p := tea.NewProgram(initialModel())
+		p := tea.NewProgram(initialModel(backend))
 		if _, err := p.Run(); err != nil {
 			fmt.Printf("Alas, there's been an error: %v", err)
-			os.Exit(1)
+			os.Exit(exitFailure)
 		}
-		os.Exit(0)
+		os.Exit(exitOK)
 	}
 
 	// Move to trash
-	for _, path := range args {
-		err := MoveToTrashBox(path)
-		if err != nil {
-			fmt.Println("go-trash: ", err)
-		}
+	trashed, err := trashPaths(backend, args, rmOpts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "go-trash: ", err)
+	}
+	if err := recordBatch(backend, trashed); err != nil {
+		fmt.Fprintln(os.Stderr, "go-trash:  failed to record the command for --undo:", err)
 	}
+	os.Exit(exitCode(err))
 }
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestCheckOptions(t *testing.T) {
+	tests := []struct {
+		seen []string
+		args []string
+		ok   bool
+	}{
+		{nil, nil, true},
+		{nil, []string{"f"}, true},
+		{[]string{"-r", "-f"}, []string{"d"}, true},
+		{[]string{"-f"}, nil, true},
+		{[]string{"-u", "--glob", "-o", "--conflict", "--parents"}, nil, true},
+		{[]string{"-u", "--restore", "--since", "--yes"}, nil, true},
+		{[]string{"--restore", "--between"}, []string{"1m"}, true},
+		{[]string{"--undo", "--conflict"}, []string{"2"}, true},
+		{[]string{"-l", "--format", "--sort", "--reverse", "--since", "--until", "--bytes"}, nil, true},
+		{[]string{"--empty", "--all-volumes", "--yes"}, nil, true},
+		{[]string{"--purge", "--yes"}, []string{"*.log"}, true},
+		{[]string{"--older-than", "--max-size", "--keep-newer-than"}, nil, true},
+		{[]string{"--si"}, nil, true},
+		{[]string{"--trash-dir", "-l"}, nil, true},
+
+		{[]string{"--empty", "-u", "--yes"}, nil, false},
+		{[]string{"-l", "--purge"}, nil, false},
+		{[]string{"--older-than", "--undo"}, nil, false},
+		{[]string{"-t", "-l"}, nil, false},
+		{[]string{"--glob"}, []string{"f"}, false},
+		{[]string{"--restore", "--glob"}, nil, false},
+		{[]string{"-o"}, []string{"f"}, false},
+		{[]string{"--conflict"}, nil, false},
+		{[]string{"--format"}, nil, false},
+		{[]string{"--template"}, []string{"f"}, false},
+		{[]string{"--sort", "-u"}, nil, false},
+		{[]string{"--all-volumes"}, nil, false},
+		{[]string{"--keep-newer-than"}, nil, false},
+		{[]string{"--si"}, []string{"f"}, false},
+		{[]string{"-r", "-l"}, nil, false},
+		{[]string{"-l"}, []string{"f"}, false},
+		{[]string{"--empty"}, []string{"f"}, false},
+		{[]string{"-u"}, []string{"f"}, false},
+	}
+	for _, tt := range tests {
+		err := checkOptions(tt.seen, tt.args)
+		if (err == nil) != tt.ok {
+			t.Errorf("checkOptions(%v, %v) = %v, want ok %v", tt.seen, tt.args, err, tt.ok)
+		}
+	}
+}
+
+func TestTableOverwriteListsOverwrittenFile(t *testing.T) {
+	dir := t.TempDir()
+	location := filepath.Join(dir, "f")
+	if err := os.WriteFile(location, []byte("newer"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	m := &memTrash{items: []fi{{filename: "f", location: location, inTrashBox: "/mem/files/f.old", size: 3}}}
+
+	var model tea.Model = initialModel(m).sub
+	for _, key := range []string{"U", "o"} {
+		model, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+	}
+
+	tm := model.(tableModel)
+	if len(tm.trashList) != 1 || tm.trashList[0].size != 5 {
+		t.Errorf("trashList = %v, want only the overwritten file", tm.trashList)
+	}
+	if len(tm.allRows) != 1 || len(tm.table.Rows()) != 1 {
+		t.Errorf("%d rows, %d shown, want 1", len(tm.allRows), len(tm.table.Rows()))
+	}
+}
+
+func TestTableRestoreCreatesMissingParents(t *testing.T) {
+	dir := t.TempDir()
+	location := filepath.Join(dir, "gone", "sub", "f")
+	m := &memTrash{items: []fi{{filename: "f", location: location, inTrashBox: "/mem/files/f"}}}
+
+	var model tea.Model = initialModel(m).sub
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("U")})
+	if view := model.View(); !strings.Contains(view, filepath.Dir(location)+" does not exist. Create it?") {
+		t.Errorf("view does not ask to create the parent directory:\n%s", view)
+	}
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("y")})
+
+	if _, err := os.Stat(filepath.Dir(location)); err != nil {
+		t.Errorf("parent directory was not created: %v", err)
+	}
+	if tm := model.(tableModel); len(m.items) != 0 || len(tm.trashList) != 0 {
+		t.Errorf("f is still in the trash: %v (status %q)", m.items, tm.status)
+	}
+}
+
+func TestTableRestoreDeclinedKeepsItem(t *testing.T) {
+	location := filepath.Join(t.TempDir(), "gone", "f")
+	m := &memTrash{items: []fi{{filename: "f", location: location, inTrashBox: "/mem/files/f"}}}
+
+	var model tea.Model = initialModel(m).sub
+	for _, key := range []string{"U", "n"} {
+		model, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+	}
+	if exists(filepath.Dir(location)) || len(m.items) != 1 {
+		t.Errorf("declined restore created %s or restored f", filepath.Dir(location))
+	}
+}
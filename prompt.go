@@ -0,0 +1,27 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// stdin is shared by all prompts, so that answers piped in several lines are not lost to buffering.
+var stdin = bufio.NewScanner(os.Stdin)
+
+// ask prints prompt and reads one line from stdin. It returns false at the end of input.
+func ask(prompt string) (string, bool) {
+	fmt.Print(prompt)
+	if !stdin.Scan() {
+		return "", false
+	}
+	return strings.TrimSpace(stdin.Text()), true
+}
+
+// confirm asks a yes/no question on stdin. Anything but "y" or "yes" is a no.
+func confirm(prompt string) bool {
+	answer, _ := ask(prompt + " [y/N]: ")
+	answer = strings.ToLower(answer)
+	return answer == "y" || answer == "yes"
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// purgeItems permanently deletes files and returns the number of bytes reclaimed.
+// Failures are reported and the remaining items are still purged.
+// With verbose, each item is printed once it is purged.
+func purgeItems(b Backend, files []fi, verbose bool) (int64, error) {
+	var reclaimed int64
+	var failed int
+	for _, file := range files {
+		if err := b.Purge(file); err != nil {
+			fmt.Fprintf(os.Stderr, "go-trash:  cannot purge '%s': %v\n", file.location, err)
+			failed++
+			continue
+		}
+		reclaimed += file.size
+		if verbose {
+			printPurged(file)
+		}
+	}
+	if failed > 0 {
+		return reclaimed, &partialError{"purge", failed, len(files)}
+	}
+	return reclaimed, nil
+}
+
+func totalSize(files []fi) int64 {
+	var total int64
+	for _, file := range files {
+		total += file.size
+	}
+	return total
+}
+
+// clearer is implemented by backends that can empty whole trash directories,
+// including the entries that List does not report.
+type clearer interface {
+	// entryCount returns the number of entries, reported or not, that clear would delete.
+	entryCount(allVolumes bool) int
+	clear(allVolumes bool) (int64, error)
+}
+
+// emptyTrash permanently deletes everything in the home trash,
+// and in the trash of every volume if allVolumes is set.
+func emptyTrash(b Backend, allVolumes bool, assumeYes bool) error {
+	files, err := b.List()
+	if err != nil {
+		return err
+	}
+
+	var targets []fi
+	for _, file := range files {
+		if allVolumes || file.volume == "" {
+			targets = append(targets, file)
+		}
+	}
+
+	// Entries that List does not show, such as files without an info file, are cleared too
+	c, canClear := b.(clearer)
+	stray := 0
+	if canClear {
+		stray = c.entryCount(allVolumes) - 2*len(targets)
+	}
+	if len(targets) == 0 && stray <= 0 {
+		fmt.Println("Trash is already empty.")
+		return nil
+	}
+
+	prompt := fmt.Sprintf("Permanently delete %d items (%d bytes)", len(targets), totalSize(targets))
+	if stray > 0 {
+		prompt += fmt.Sprintf(" and %d stray entries", stray)
+	}
+	if !assumeYes && !confirm(prompt+"?") {
+		return nil
+	}
+
+	var reclaimed int64
+	if canClear {
+		reclaimed, err = c.clear(allVolumes)
+	} else {
+		reclaimed, err = purgeItems(b, targets, false)
+	}
+	fmt.Printf("Emptied trash: %d bytes reclaimed\n", reclaimed)
+	return err
+}
+
+var dayUnits = regexp.MustCompile(`(\d+(?:\.\d+)?)([dw])`)
+
+// parseAge parses a duration such as "30d", "2w" or "12h", which must not be negative.
+// On top of time.ParseDuration, "d" (days) and "w" (weeks) are accepted.
+func parseAge(s string) (time.Duration, error) {
+	expanded := dayUnits.ReplaceAllStringFunc(s, func(m string) string {
+		sub := dayUnits.FindStringSubmatch(m)
+		n, _ := strconv.ParseFloat(sub[1], 64)
+		if sub[2] == "w" {
+			n *= 7
+		}
+		return strconv.FormatFloat(n*24, 'f', -1, 64) + "h"
+	})
+	d, err := time.ParseDuration(expanded)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("invalid duration %q, it must not be negative", s)
+	}
+	return d, nil
+}
+
+// parsePurgeAge parses the age of --older-than and --keep-newer-than.
+// Unlike a time given as an age, it must be positive: --older-than 0 would purge everything.
+func parsePurgeAge(s string) (time.Duration, error) {
+	d, err := parseAge(s)
+	if err != nil {
+		return 0, err
+	}
+	if d == 0 {
+		return 0, fmt.Errorf("invalid duration %q, it must be positive", s)
+	}
+	return d, nil
+}
+
+func printPurged(file fi) {
+	fmt.Printf("Purge %s (deleted %s, %d bytes)\n", file.location, file.dateDeleted.Format("2006-01-02T15:04:05Z07:00"), file.size)
+}
+
+// purgePolicy decides which items are purged automatically.
+type purgePolicy struct {
+	olderThan     time.Duration // purge items trashed more than this long ago, 0 for no limit
+	maxSize       int64         // evict the oldest items until the trash fits, negative for no limit
+	keepNewerThan time.Duration // never purge items trashed less than this long ago
+}
+
+// selectPurge returns the items to purge under p.
+// Items without a valid deletion date are never purged by age, but are evicted first to fit maxSize.
+func selectPurge(files []fi, p purgePolicy, now time.Time) []fi {
+	sorted := append([]fi(nil), files...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].dateDeleted.Before(sorted[j].dateDeleted)
+	})
+
+	total := totalSize(sorted)
+	var targets []fi
+	for _, file := range sorted {
+		if p.keepNewerThan > 0 && file.dateDeleted.After(now.Add(-p.keepNewerThan)) {
+			continue
+		}
+		expired := p.olderThan > 0 && !file.dateDeleted.IsZero() && file.dateDeleted.Before(now.Add(-p.olderThan))
+		overBudget := p.maxSize >= 0 && total > p.maxSize
+		if expired || overBudget {
+			targets = append(targets, file)
+			total -= file.size
+		}
+	}
+	return targets
+}
+
+// purgeTrash permanently deletes the items selected by p.
+func purgeTrash(b Backend, p purgePolicy) error {
+	files, err := b.List()
+	if err != nil {
+		return err
+	}
+
+	targets := selectPurge(files, p, time.Now())
+
+	reclaimed, err := purgeItems(b, targets, true)
+	fmt.Printf("Purged %d items: %d bytes freed\n", len(targets), reclaimed)
+	return err
+}
+
+var sizePattern = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*(?:([KMGTP])(IB|B)?|B)?$`)
+
+// parseSize parses a size such as "500M" or "20G".
+// As in coreutils, "K", "M", ... and "KiB", "MiB", ... are powers of 1024, "KB", "MB", ... powers of 1000.
+func parseSize(s string) (int64, error) {
+	m := sizePattern.FindStringSubmatch(strings.ToUpper(strings.TrimSpace(s)))
+	if m == nil {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	n, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	if m[2] == "" {
+		return int64(n), nil
+	}
+
+	base := 1024.0
+	if m[3] == "B" {
+		base = 1000
+	}
+	exp := strings.Index("KMGTP", m[2]) + 1
+	return int64(n * math.Pow(base, float64(exp))), nil
+}
+
+// newPurgePolicy builds a purgePolicy from the --older-than, --max-size and --keep-newer-than values.
+func newPurgePolicy(olderThan string, maxSize string, keepNewerThan string) (purgePolicy, error) {
+	p := purgePolicy{maxSize: -1}
+	var err error
+	if olderThan != "" {
+		if p.olderThan, err = parsePurgeAge(olderThan); err != nil {
+			return p, err
+		}
+	}
+	if maxSize != "" {
+		if p.maxSize, err = parseSize(maxSize); err != nil {
+			return p, err
+		}
+	}
+	if keepNewerThan != "" {
+		if p.keepNewerThan, err = parsePurgeAge(keepNewerThan); err != nil {
+			return p, err
+		}
+	}
+	return p, nil
+}
+
+// matchSelector reports whether file is selected by sel, which is either
+// a stable ID, an original path (selecting everything under it if it is a directory)
+// or a glob pattern matched against the file name.
+func matchSelector(file fi, sel string) bool {
+	if stableID(file) == sel {
+		return true
+	}
+	if strings.ContainsRune(sel, filepath.Separator) {
+		return isUnder(file.location, sel)
+	}
+	ok, _ := filepath.Match(sel, file.filename)
+	return ok
+}
+
+// purgeSelected permanently deletes the items matched by selectors, after listing them for confirmation.
+func purgeSelected(b Backend, selectors []string, assumeYes bool) error {
+	files, err := b.List()
+	if err != nil {
+		return err
+	}
+
+	var targets []fi
+	for _, sel := range selectors {
+		matched := false
+		for _, file := range files {
+			if matchSelector(file, sel) {
+				matched = true
+				if !slices.ContainsFunc(targets, func(t fi) bool { return t.inTrashBox == file.inTrashBox }) {
+					targets = append(targets, file)
+				}
+			}
+		}
+		if !matched {
+			return fmt.Errorf("no trashed item matches %s", sel)
+		}
+	}
+
+	fmt.Printf("%-10s%-12s%s\n", "ID", "Size", "Location")
+	for _, file := range targets {
+		fmt.Printf("%-10s%-12d%s\n", stableID(file), file.size, file.location)
+	}
+	if !assumeYes && !confirm(fmt.Sprintf("Permanently delete these %d items (%d bytes)?", len(targets), totalSize(targets))) {
+		return nil
+	}
+
+	reclaimed, err := purgeItems(b, targets, false)
+	fmt.Printf("Purged %d items: %d bytes freed\n", len(targets), reclaimed)
+	return err
+}
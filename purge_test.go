@@ -0,0 +1,125 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+		ok   bool
+	}{
+		{"12h", 12 * time.Hour, true},
+		{"30d", 30 * 24 * time.Hour, true},
+		{"2w", 14 * 24 * time.Hour, true},
+		{"1.5d", 36 * time.Hour, true},
+		{"1d12h", 36 * time.Hour, true},
+		{"90m", 90 * time.Minute, true},
+		{"", 0, false},
+		{"30", 0, false},
+		{"soon", 0, false},
+		{"-1d", 0, false},
+		{"-12h", 0, false},
+		{"0d", 0, true},
+		{"0s", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseAge(tt.in)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("parseAge(%q) = %v, %v, want %v (ok %v)", tt.in, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+		ok   bool
+	}{
+		{"100", 100, true},
+		{"100B", 100, true},
+		{"1K", 1024, true},
+		{"1KiB", 1024, true},
+		{"1KB", 1000, true},
+		{"500M", 500 << 20, true},
+		{"1.5G", 3 << 29, true},
+		{"20gib", 20 << 30, true},
+		{"2 GB", 2e9, true},
+		{"", 0, false},
+		{"-1G", 0, false},
+		{"1X", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := parseSize(tt.in)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("parseSize(%q) = %v, %v, want %v (ok %v)", tt.in, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestSelectPurge(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	files := []fi{
+		{filename: "new", size: 10, dateDeleted: now.Add(-time.Hour)},
+		{filename: "old", size: 100, dateDeleted: now.Add(-40 * day)},
+		{filename: "undated", size: 5},
+		{filename: "mid", size: 50, dateDeleted: now.Add(-10 * day)},
+	}
+
+	tests := []struct {
+		name string
+		p    purgePolicy
+		want []string
+	}{
+		{"nothing", purgePolicy{maxSize: -1}, nil},
+		{"older than", purgePolicy{olderThan: 30 * day, maxSize: -1}, []string{"old"}},
+		{"older than all dated", purgePolicy{olderThan: time.Minute, maxSize: -1}, []string{"old", "mid", "new"}},
+		{"max size evicts oldest first", purgePolicy{maxSize: 60}, []string{"undated", "old"}},
+		{"max size fits", purgePolicy{maxSize: 165}, nil},
+		{"keep newer than", purgePolicy{olderThan: time.Minute, maxSize: -1, keepNewerThan: 2 * time.Hour}, []string{"old", "mid"}},
+		{"keep newer than with max size", purgePolicy{maxSize: 0, keepNewerThan: 20 * day}, []string{"undated", "old"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, file := range selectPurge(files, tt.p, now) {
+				got = append(got, file.filename)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("selectPurge() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPurgeTrash(t *testing.T) {
+	now := time.Now()
+	m := &memTrash{items: []fi{
+		{filename: "old", location: "/home/user/old", inTrashBox: "/mem/files/old", dateDeleted: now.Add(-48 * time.Hour)},
+		{filename: "new", location: "/home/user/new", inTrashBox: "/mem/files/new", dateDeleted: now, size: 1},
+	}}
+	if err := purgeTrash(m, purgePolicy{olderThan: 24 * time.Hour, maxSize: -1}); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.items) != 1 || m.items[0].filename != "new" {
+		t.Errorf("trash after purge = %v, want only new", m.items)
+	}
+
+	if _, err := newPurgePolicy("", "1G", "-1h"); err == nil {
+		t.Error("newPurgePolicy accepted a negative --keep-newer-than")
+	}
+	if _, err := newPurgePolicy("0s", "", ""); err == nil {
+		t.Error("newPurgePolicy accepted --older-than 0s")
+	}
+
+	m.failOn = map[string]bool{"/home/user/new": true}
+	err := purgeTrash(m, purgePolicy{maxSize: 0})
+	if code := exitCode(err); code != exitFailure {
+		t.Errorf("exitCode(%v) = %d, want %d", err, code, exitFailure)
+	}
+}
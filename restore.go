@@ -0,0 +1,420 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// restoreDestination returns where file is restored to.
+// Without outputPath it is the original location. If outputPath is a directory
+// (an existing one, or any path ending with a separator) the file keeps its name inside it.
+func restoreDestination(file fi, outputPath string) string {
+	if outputPath == "" {
+		return file.location
+	}
+	if isDirPath(outputPath) {
+		return filepath.Join(outputPath, file.filename)
+	}
+	return outputPath
+}
+
+func isDirPath(path string) bool {
+	if strings.HasSuffix(path, string(filepath.Separator)) || strings.HasSuffix(path, "/") {
+		return true
+	}
+	st, err := os.Stat(path)
+	return err == nil && st.IsDir()
+}
+
+// What to do when the restore destination already exists
+type conflictPolicy string
+
+const (
+	conflictAsk       conflictPolicy = "ask"
+	conflictSkip      conflictPolicy = "skip"
+	conflictRename    conflictPolicy = "rename"
+	conflictOverwrite conflictPolicy = "overwrite"
+)
+
+func parseConflictPolicy(s string) (conflictPolicy, error) {
+	switch p := conflictPolicy(s); p {
+	case conflictAsk, conflictSkip, conflictRename, conflictOverwrite:
+		return p, nil
+	}
+	return "", fmt.Errorf("invalid conflict policy %q (ask, skip, rename or overwrite)", s)
+}
+
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+// restoredName returns a free name next to dst: "name (restored).ext", "name (restored 2).ext", ...
+func restoredName(dst string) string {
+	dir := filepath.Dir(dst)
+	ext := filepath.Ext(dst)
+	stem := strings.TrimSuffix(filepath.Base(dst), ext)
+	if stem == "" {
+		// Dot files such as ".bashrc" have no extension
+		stem, ext = ext, ""
+	}
+
+	for n := 1; ; n++ {
+		suffix := " (restored)"
+		if n > 1 {
+			suffix = fmt.Sprintf(" (restored %d)", n)
+		}
+		candidate := filepath.Join(dir, stem+suffix+ext)
+		if !exists(candidate) {
+			return candidate
+		}
+	}
+}
+
+func askConflict(dst string) conflictPolicy {
+	answer, _ := ask(dst + " already exists. [s]kip, [r]ename, [o]verwrite? ")
+	switch strings.ToLower(answer) {
+	case "r", "rename":
+		return conflictRename
+	case "o", "overwrite":
+		return conflictOverwrite
+	}
+	return conflictSkip
+}
+
+// resolveConflict returns the path to restore to when dst may already exist,
+// or "" if the item must be skipped. Overwritten files are moved to the trash, not deleted.
+func resolveConflict(b Backend, dst string, policy conflictPolicy) (string, error) {
+	if !exists(dst) {
+		return dst, nil
+	}
+	if policy == conflictAsk {
+		policy = askConflict(dst)
+	}
+
+	switch policy {
+	case conflictRename:
+		return restoredName(dst), nil
+	case conflictOverwrite:
+		if _, err := b.Put(dst); err != nil {
+			return "", err
+		}
+		return dst, nil
+	}
+	return "", nil
+}
+
+// missingParents returns the directories above path that do not exist, outermost first.
+func missingParents(path string) []string {
+	var missing []string
+	for dir := filepath.Dir(path); !exists(dir); dir = filepath.Dir(dir) {
+		missing = append([]string{dir}, missing...)
+		if dir == filepath.Dir(dir) {
+			break
+		}
+	}
+	return missing
+}
+
+// createParents recreates the missing directories above dst, asking first unless auto is set.
+// It reports whether dst can be restored, and prints each directory it creates.
+func createParents(dst string, auto bool) (bool, error) {
+	missing := missingParents(dst)
+	if len(missing) == 0 {
+		return true, nil
+	}
+	if !auto && !confirm(fmt.Sprintf("%s does not exist. Create it?", filepath.Dir(dst))) {
+		return false, nil
+	}
+
+	for _, dir := range missing {
+		if err := mkdirParent(dir); err != nil {
+			return false, err
+		}
+		fmt.Printf("Created directory %s\n", dir)
+	}
+	return true, nil
+}
+
+// mkdirParent creates one of the directories returned by missingParents.
+func mkdirParent(dir string) error {
+	// 0777 leaves the permissions to the umask, as mkdir does
+	if err := os.Mkdir(dir, 0777); err != nil && !os.IsExist(err) {
+		return err
+	}
+	return nil
+}
+
+// parseSelection parses the answer to the restore picker: numbers separated by commas,
+// ranges such as "2-4", or "a" for all. Numbers start at 1, as in the TUI, and must not exceed n.
+// The selected items are returned as indexes starting at 0.
+func parseSelection(answer string, n int) ([]int, error) {
+	if answer == "a" || answer == "all" {
+		all := make([]int, n)
+		for i := range all {
+			all[i] = i
+		}
+		return all, nil
+	}
+
+	var ids []int
+	for _, part := range strings.Split(answer, ",") {
+		part = strings.TrimSpace(part)
+		first, last, isRange := strings.Cut(part, "-")
+		from, err := strconv.Atoi(strings.TrimSpace(first))
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", part)
+		}
+		to := from
+		if isRange {
+			if to, err = strconv.Atoi(strings.TrimSpace(last)); err != nil {
+				return nil, fmt.Errorf("invalid range %q", part)
+			}
+		}
+		if from < 1 || to > n || from > to {
+			return nil, fmt.Errorf("%q is out of range 1-%d", part, n)
+		}
+		for id := from - 1; id < to; id++ {
+			if !slices.Contains(ids, id) {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids, nil
+}
+
+func printRestoreTable(files []fi) {
+	fmt.Printf("%-9s%-32s%-16s%s\n", "#", "DateDeleted", "FileSize", "Path")
+	for i, file := range files {
+		fmt.Printf("%-9d%-32s%-16s%s\n", i+1, file.dateDeleted.Format("2006-01-02 15:04:05"), formatSize(file.size), file.location)
+	}
+}
+
+// pickItems shows files as a numbered table and returns the ones the user selects
+// with parseSelection. An empty answer cancels the restore.
+func pickItems(files []fi) []fi {
+	printRestoreTable(files)
+
+	for {
+		answer, ok := ask("Which one do you restore? > ")
+		if !ok || answer == "" {
+			return nil
+		}
+		ids, err := parseSelection(answer, len(files))
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+
+		var picked []fi
+		for _, id := range ids {
+			picked = append(picked, files[id])
+		}
+		return picked
+	}
+}
+
+// How -u selects the items to restore
+type matchMode uint
+
+const (
+	matchName  matchMode = iota // exact file name
+	matchGlob                   // glob pattern on the file name
+	matchRegex                  // regular expression on the file name
+	matchPath                   // original location, or a directory containing it
+	matchID                     // stable ID as shown by -l
+)
+
+// newMatcher returns a function reporting whether a trashed file is selected by pattern.
+func newMatcher(pattern string, mode matchMode) (func(fi) bool, error) {
+	switch mode {
+	case matchGlob:
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid glob %q: %w", pattern, err)
+		}
+		return func(file fi) bool {
+			ok, _ := filepath.Match(pattern, file.filename)
+			return ok
+		}, nil
+	case matchRegex:
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		return func(file fi) bool { return re.MatchString(file.filename) }, nil
+	case matchPath:
+		return func(file fi) bool { return isUnder(file.location, pattern) }, nil
+	case matchID:
+		return func(file fi) bool { return stableID(file) == pattern }, nil
+	}
+	return func(file fi) bool { return file.filename == pattern }, nil
+}
+
+// timeFormats are the absolute times accepted by --since and --between, in local time.
+var timeFormats = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// parseTime parses an absolute time, or an age such as "10m" or "2d" meaning that long before now.
+// "now" and an age of zero are now.
+func parseTime(s string, now time.Time) (time.Time, error) {
+	if s == "now" {
+		return now, nil
+	}
+	if age, err := parseAge(s); err == nil {
+		return now.Add(-age), nil
+	}
+	for _, layout := range timeFormats {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", s)
+}
+
+// deletedBetween selects the items trashed between from and to, both included.
+// A zero time leaves that side open.
+func deletedBetween(from time.Time, to time.Time) func(fi) bool {
+	return func(file fi) bool {
+		if file.dateDeleted.IsZero() {
+			return false
+		}
+		return !file.dateDeleted.Before(from) && (to.IsZero() || !file.dateDeleted.After(to))
+	}
+}
+
+// parseBetween parses the --between value "T1,T2".
+// As in "--between T1 T2", the second time can also be the only parameter.
+func parseBetween(between string, args []string, now time.Time) (time.Time, time.Time, error) {
+	first, second, ok := strings.Cut(between, ",")
+	if !ok {
+		if len(args) != 1 {
+			return time.Time{}, time.Time{}, fmt.Errorf("--between needs two times")
+		}
+		second = args[0]
+	}
+
+	from, err := parseTime(strings.TrimSpace(first), now)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	to, err := parseTime(strings.TrimSpace(second), now)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if to.Before(from) {
+		from, to = to, from
+	}
+	return from, to, nil
+}
+
+// allOf selects the items selected by every filter.
+func allOf(filters ...func(fi) bool) func(fi) bool {
+	return func(file fi) bool {
+		for _, f := range filters {
+			if !f(file) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+type restoreOptions struct {
+	outputPath string // restore to this path instead of the original location
+	conflict   conflictPolicy
+	parents    bool // recreate missing parent directories without asking
+	batch      bool // restore every match after a confirmation instead of picking them by number
+	assumeYes  bool // do not ask for the batch confirmation
+}
+
+// undelete restores the trashed files selected by match,
+// to their original location or to opts.outputPath.
+// what describes the selection in error messages.
+func undelete(b Backend, match func(fi) bool, what string, opts restoreOptions) error {
+	trashfiles, err := b.List()
+	if err != nil {
+		return err
+	}
+
+	var udFileList []fi
+	for _, file := range trashfiles {
+		if match(file) {
+			udFileList = append(udFileList, file)
+		}
+	}
+
+	if len(udFileList) == 0 {
+		return fmt.Errorf("no trashed file matches %s", what)
+	}
+	if opts.batch {
+		printRestoreTable(udFileList)
+		if !opts.assumeYes && !confirm(fmt.Sprintf("Restore these %d items?", len(udFileList))) {
+			return nil
+		}
+	} else if len(udFileList) > 1 {
+		udFileList = pickItems(udFileList)
+		if len(udFileList) == 0 {
+			return nil
+		}
+	}
+	outputPath := opts.outputPath
+	if len(udFileList) > 1 && outputPath != "" && !isDirPath(outputPath) {
+		return fmt.Errorf("%d files selected, -o must be a directory to restore them into", len(udFileList))
+	}
+
+	if outputPath != "" && isDirPath(outputPath) {
+		if err := os.MkdirAll(outputPath, 0777); err != nil {
+			return err
+		}
+	}
+
+	// Keep going when an item fails, and report them all at the end
+	var failed int
+	for _, file := range udFileList {
+		dst := restoreDestination(file, outputPath)
+		ok, err := createParents(dst, opts.parents)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "go-trash:  cannot restore '%s' to '%s': %v\n", file.filename, dst, err)
+			failed++
+			continue
+		}
+		if !ok {
+			fmt.Printf("Skip %s\n", file.filename)
+			continue
+		}
+
+		target, err := resolveConflict(b, dst, opts.conflict)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "go-trash:  cannot restore '%s' to '%s': %v\n", file.filename, dst, err)
+			failed++
+			continue
+		}
+		if target == "" {
+			fmt.Printf("Skip %s\n", file.filename)
+			continue
+		}
+		err = b.Restore(file, target)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "go-trash:  cannot restore '%s' to '%s': %v\n", file.filename, target, err)
+			failed++
+			continue
+		}
+		fmt.Printf("UnDelete %s → %s\n", file.filename, target)
+	}
+
+	if failed > 0 {
+		return &partialError{"restore", failed, len(udFileList)}
+	}
+	return nil
+}
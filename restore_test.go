@@ -0,0 +1,126 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseSelection(t *testing.T) {
+	tests := []struct {
+		answer string
+		want   []int
+		ok     bool
+	}{
+		{"1", []int{0}, true},
+		{"a", []int{0, 1, 2, 3, 4}, true},
+		{"all", []int{0, 1, 2, 3, 4}, true},
+		{"1,3", []int{0, 2}, true},
+		{"2-4", []int{1, 2, 3}, true},
+		{"1, 3-5", []int{0, 2, 3, 4}, true},
+		{"4,2-4", []int{3, 1, 2}, true},
+		{"5", []int{4}, true},
+		{"0", nil, false},
+		{"6", nil, false},
+		{"-1", nil, false},
+		{"3-1", nil, false},
+		{"x", nil, false},
+		{"1-x", nil, false},
+	}
+	for _, tt := range tests {
+		got, err := parseSelection(tt.answer, 5)
+		if (err == nil) != tt.ok || !slices.Equal(got, tt.want) {
+			t.Errorf("parseSelection(%q, 5) = %v, %v, want %v (ok %v)", tt.answer, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestNewMatcher(t *testing.T) {
+	report := fi{
+		filename:    "report.txt",
+		location:    "/home/user/docs/report.txt",
+		inTrashBox:  "/home/user/.local/share/Trash/files/report.txt",
+		dateDeleted: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	tests := []struct {
+		name    string
+		pattern string
+		mode    matchMode
+		want    bool
+	}{
+		{"name", "report.txt", matchName, true},
+		{"name is exact", "report", matchName, false},
+		{"glob", "*.txt", matchGlob, true},
+		{"glob mismatch", "*.go", matchGlob, false},
+		{"regex", `^rep.*\.txt$`, matchRegex, true},
+		{"regex mismatch", `^txt`, matchRegex, false},
+		{"path", "/home/user/docs/report.txt", matchPath, true},
+		{"path of directory", "/home/user/docs", matchPath, true},
+		{"path of directory with separator", "/home/user/docs/", matchPath, true},
+		{"path prefix is not a directory", "/home/user/do", matchPath, false},
+		{"id", stableID(report), matchID, true},
+		{"id mismatch", "00000000", matchID, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match, err := newMatcher(tt.pattern, tt.mode)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := match(report); got != tt.want {
+				t.Errorf("match(%q) = %v, want %v", tt.pattern, got, tt.want)
+			}
+		})
+	}
+
+	if _, err := newMatcher("[", matchGlob); err == nil {
+		t.Error("newMatcher accepted an invalid glob")
+	}
+	if _, err := newMatcher("(", matchRegex); err == nil {
+		t.Error("newMatcher accepted an invalid regular expression")
+	}
+}
+
+// A reused trash name must not give a later item the ID of an earlier one.
+func TestStableIDChangesWithItem(t *testing.T) {
+	a := fi{location: "/a/n.txt", inTrashBox: "/trash/files/n.txt", deletionDate: "2024-01-02T03:04:05"}
+	b := fi{location: "/b/n.txt", inTrashBox: "/trash/files/n.txt", deletionDate: "2024-01-02T03:04:05"}
+	later := a
+	later.deletionDate = "2024-01-02T03:04:06"
+	if stableID(a) == stableID(b) || stableID(a) == stableID(later) {
+		t.Errorf("stableID is shared by different items: %s %s %s", stableID(a), stableID(b), stableID(later))
+	}
+	if stableID(a) != stableID(a) {
+		t.Error("stableID is not stable")
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.Local)
+	tests := []struct {
+		in   string
+		want time.Time
+		ok   bool
+	}{
+		{"now", now, true},
+		{"0s", now, true},
+		{"0", now, true},
+		{"10m", now.Add(-10 * time.Minute), true},
+		{"2d", now.Add(-48 * time.Hour), true},
+		{"2024-01-02 15:04", time.Date(2024, 1, 2, 15, 4, 0, 0, time.Local), true},
+		{"2024-01-02T15:04:05Z", time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC), true},
+		{"-1h", time.Time{}, false},
+		{"soon", time.Time{}, false},
+	}
+	for _, tt := range tests {
+		got, err := parseTime(tt.in, now)
+		if (err == nil) != tt.ok || !got.Equal(tt.want) {
+			t.Errorf("parseTime(%q) = %v, %v, want %v (ok %v)", tt.in, got, err, tt.want, tt.ok)
+		}
+	}
+
+	from, to, err := parseBetween("1h", []string{"0s"}, now)
+	if err != nil || !from.Equal(now.Add(-time.Hour)) || !to.Equal(now) {
+		t.Errorf("parseBetween(1h, 0s) = %v, %v, %v, want the last hour", from, to, err)
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// When the trash command asks for confirmation, as rm's -f, -i and -I
+type promptMode uint
+
+const (
+	promptNever  promptMode = iota
+	promptOnce              // -I: once before trashing more than three files or recursively
+	promptAlways            // -i: before every file
+)
+
+// rmOptions are the rm-compatible options of the trash command.
+type rmOptions struct {
+	recursive bool // -r, -R: trash directories and their contents
+	emptyDirs bool // -d: trash empty directories
+	force     bool // -f: ignore missing files and never prompt
+	prompt    promptMode
+	verbose   bool // -v: explain what is being done
+}
+
+// describe names the kind of file as rm does in its prompts.
+func describe(info os.FileInfo) string {
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		return "symbolic link"
+	case info.IsDir():
+		return "directory"
+	case info.Mode().IsRegular() && info.Size() == 0:
+		return "regular empty file"
+	case info.Mode().IsRegular():
+		return "regular file"
+	}
+	return "file"
+}
+
+func isEmptyDir(path string) bool {
+	d, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer d.Close()
+	_, err = d.Readdirnames(1)
+	return err == io.EOF
+}
+
+// trashPaths moves paths to the trash with the semantics of rm: directories need -r
+// (or -d when they are empty), missing files are ignored with -f, and -i and -I prompt.
+// Each failure is reported and the remaining paths are still trashed.
+// Missing files ignored with -f and files declined at a prompt are not failures.
+func trashPaths(b Backend, paths []string, opts rmOptions) ([]fi, error) {
+	if opts.prompt == promptOnce && (opts.recursive || len(paths) > 3) {
+		prompt := fmt.Sprintf("go-trash: trash %d arguments?", len(paths))
+		if opts.recursive {
+			prompt = fmt.Sprintf("go-trash: trash %d arguments recursively?", len(paths))
+		}
+		if !confirm(prompt) {
+			return nil, nil
+		}
+	}
+
+	var trashed []fi
+	var failed int
+	for _, path := range paths {
+		if base := filepath.Base(path); base == "." || base == ".." {
+			fmt.Fprintf(os.Stderr, "go-trash:  refusing to trash '.' or '..' directory: skipping '%s'\n", path)
+			failed++
+			continue
+		}
+
+		info, err := os.Lstat(path)
+		if os.IsNotExist(err) {
+			if !opts.force {
+				fmt.Fprintf(os.Stderr, "go-trash:  cannot trash '%s': No such file or directory\n", path)
+				failed++
+			}
+			continue
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "go-trash:  cannot trash '%s': %v\n", path, err)
+			failed++
+			continue
+		}
+
+		if info.IsDir() && !opts.recursive {
+			if !opts.emptyDirs {
+				fmt.Fprintf(os.Stderr, "go-trash:  cannot trash '%s': Is a directory\n", path)
+				failed++
+				continue
+			}
+			if !isEmptyDir(path) {
+				fmt.Fprintf(os.Stderr, "go-trash:  cannot trash '%s': Directory not empty\n", path)
+				failed++
+				continue
+			}
+		}
+
+		if opts.prompt == promptAlways && !confirm(fmt.Sprintf("go-trash: trash %s '%s'?", describe(info), path)) {
+			continue
+		}
+
+		file, err := b.Put(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "go-trash:  cannot trash '%s': %v\n", path, err)
+			failed++
+			continue
+		}
+		if opts.verbose {
+			if info.IsDir() {
+				fmt.Printf("trashed directory '%s'\n", path)
+			} else {
+				fmt.Printf("trashed '%s'\n", path)
+			}
+		}
+		trashed = append(trashed, file)
+	}
+
+	if failed > 0 {
+		return trashed, &partialError{"trash", failed, len(paths)}
+	}
+	return trashed, nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestTrashPaths(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"file", "full/file"} {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0700)
+		if err := os.WriteFile(path, []byte("x"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "empty"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		paths    []string
+		opts     rmOptions
+		want     []string // file names put into the trash
+		exitCode int
+	}{
+		{"file", []string{"file"}, rmOptions{}, []string{"file"}, exitOK},
+		{"directory needs -r", []string{"full"}, rmOptions{}, nil, exitFailure},
+		{"directory with -r", []string{"full"}, rmOptions{recursive: true}, []string{"full"}, exitOK},
+		{"empty directory with -d", []string{"empty"}, rmOptions{emptyDirs: true}, []string{"empty"}, exitOK},
+		{"full directory with -d", []string{"full"}, rmOptions{emptyDirs: true}, nil, exitFailure},
+		{"missing", []string{"missing", "file"}, rmOptions{}, []string{"file"}, exitPartial},
+		{"missing with -f", []string{"missing", "file"}, rmOptions{force: true}, []string{"file"}, exitOK},
+		{"dot", []string{".", "file"}, rmOptions{recursive: true}, []string{"file"}, exitPartial},
+		{"dot dot", []string{"full/.."}, rmOptions{recursive: true}, nil, exitFailure},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var paths []string
+			for _, p := range tt.paths {
+				// Not filepath.Join, which would clean "." and ".." away
+				paths = append(paths, dir+string(filepath.Separator)+p)
+			}
+
+			m := &memTrash{}
+			trashed, err := trashPaths(m, paths, tt.opts)
+			var got []string
+			for _, file := range trashed {
+				got = append(got, file.filename)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("trashed %v, want %v", got, tt.want)
+			}
+			if len(m.items) != len(trashed) {
+				t.Errorf("%d items in the trash, want %d", len(m.items), len(trashed))
+			}
+			if code := exitCode(err); code != tt.exitCode {
+				t.Errorf("exitCode(%v) = %d, want %d", err, code, tt.exitCode)
+			}
+		})
+	}
+}
+
+func TestTrashPathsReportsPutFailures(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a"), filepath.Join(dir, "b")
+	for _, p := range []string{a, b} {
+		if err := os.WriteFile(p, nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m := &memTrash{failOn: map[string]bool{a: true}}
+	trashed, err := trashPaths(m, []string{a, b}, rmOptions{})
+	if len(trashed) != 1 || trashed[0].location != b {
+		t.Errorf("trashed %v, want only %s", trashed, b)
+	}
+	if code := exitCode(err); code != exitPartial {
+		t.Errorf("exitCode(%v) = %d, want %d", err, code, exitPartial)
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type fi struct {
+	id           string // I know bad. but the type use in []table.Row{} is string
+	filename     string
+	location     string
+	inTrashBox   string
+	dateDeleted  time.Time
+	deletionDate string // dateDeleted as recorded by the trash, which does not depend on the time zone
+	size         int64
+	volume       string // top directory of the volume trash holding the item, empty for the home trash
+	kind         string // kindFile, kindDir or kindSymlink
+}
+
+const (
+	kindFile    = "file"
+	kindDir     = "dir"
+	kindSymlink = "symlink"
+)
+
+func kindOf(mode os.FileMode) string {
+	switch {
+	case mode&os.ModeSymlink != 0:
+		return kindSymlink
+	case mode.IsDir():
+		return kindDir
+	}
+	return kindFile
+}
+
+// Backend is a trash implementation.
+// The freedesktop.org trash is used on Linux and the Recycle Bin on Windows,
+// but anything that can list, put, restore and purge items can drive go-trash.
+type Backend interface {
+	// List returns the items currently in the trash.
+	List() ([]fi, error)
+	// Put moves path into the trash and returns the trashed item.
+	Put(path string) (fi, error)
+	// Restore moves a trashed item back to dstPath.
+	Restore(file fi, dstPath string) error
+	// Purge permanently deletes a trashed item.
+	Purge(file fi) error
+}
+
+// stableID returns an ID for file that does not change while it stays in the trash,
+// unlike fi.id which is its position in a listing.
+// Trash names are reused once an item leaves the trash, so the original location
+// and the deletion date are hashed too: a later item with the same trash name gets another ID.
+// The deletion date is hashed as the trash records it, so the ID is the same in every time zone.
+func stableID(file fi) string {
+	key := strings.Join([]string{file.inTrashBox, file.location, file.deletionDate}, "\x00")
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:4])
+}
+
+// isUnder reports whether location is path itself or inside the directory path.
+func isUnder(location string, path string) bool {
+	prefix, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	return location == prefix || strings.HasPrefix(location, strings.TrimSuffix(prefix, string(filepath.Separator))+string(filepath.Separator))
+}
+
+func printDisplayName(line string, label string) {
+	fmt.Printf("%-12s: %s\n", label, line)
+}
+
+func PrintTrashBoxItems(files []fi) {
+	for _, file := range files {
+		fmt.Println()
+		printDisplayName(stableID(file), "ID")
+		printDisplayName(file.filename, "FileName")
+		printDisplayName(file.location, "Location")
+		printDisplayName(file.inTrashBox, "InTrashBox")
+		printDisplayName(file.dateDeleted.Format("2006-01-02T15:04:05Z07:00"), "DateDeleted")
+		printDisplayName(formatSize(file.size), "Size")
+	}
+	fmt.Println()
+	printDisplayName(totals(files), "Total")
+}
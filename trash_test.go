@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// memTrash is an in-memory Backend. Put records the item without touching the file,
+// so tests can drive the trash commands without a trash directory.
+type memTrash struct {
+	items   []fi
+	failOn  map[string]bool // paths whose Put, Restore or Purge fails
+	journal string          // path of the --undo journal
+}
+
+func (m *memTrash) journalPath() string {
+	return m.journal
+}
+
+func (m *memTrash) List() ([]fi, error) {
+	return slices.Clone(m.items), nil
+}
+
+func (m *memTrash) Put(path string) (fi, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return fi{}, err
+	}
+	if m.failOn[abs] {
+		return fi{}, fmt.Errorf("cannot move %s", abs)
+	}
+	info, err := os.Lstat(abs)
+	if err != nil {
+		return fi{}, err
+	}
+	deleted := time.Now().Truncate(time.Second)
+	file := fi{
+		filename:     filepath.Base(abs),
+		location:     abs,
+		inTrashBox:   filepath.Join("/mem/files", filepath.Base(abs)),
+		dateDeleted:  deleted,
+		deletionDate: deleted.UTC().Format(time.RFC3339),
+		size:         info.Size(),
+		kind:         kindOf(info.Mode()),
+	}
+	m.items = append(m.items, file)
+	return file, nil
+}
+
+func (m *memTrash) remove(file fi) error {
+	if m.failOn[file.location] {
+		return fmt.Errorf("cannot remove %s", file.location)
+	}
+	i := slices.IndexFunc(m.items, func(f fi) bool { return f.inTrashBox == file.inTrashBox })
+	if i < 0 {
+		return os.ErrNotExist
+	}
+	m.items = slices.Delete(m.items, i, i+1)
+	return nil
+}
+
+func (m *memTrash) Restore(file fi, dstPath string) error {
+	return m.remove(file)
+}
+
+func (m *memTrash) Purge(file fi) error {
+	return m.remove(file)
+}
@@ -2,14 +2,15 @@ package main
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
-	"io/ioutil"
 	"net/url"
 	"os"
 	"os/user"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 )
 
@@ -19,56 +20,144 @@ type Info struct {
 	deletionDate time.Time
 }
 
-func parseLine(line string, delimiter string) []string {
-	pl := strings.Split(line, delimiter)
-	return pl
+// freedesktopTrash is a trash directory laid out as described in
+// https://specifications.freedesktop.org/trash-spec/trashspec-1.0.html
+type freedesktopTrash struct {
+	base string
+	// topdir is the top directory of the volume for $topdir/.Trash/$uid and $topdir/.Trash-$uid.
+	// It is empty for the home trash, whose Path= entries are absolute.
+	topdir string
 }
 
-func decodeLine(pl []string) string {
-	decodedFilePath, err := url.QueryUnescape(pl[1])
+func newFreedesktopTrash(base string) *freedesktopTrash {
+	return &freedesktopTrash{base: base}
+}
+
+// freedesktop is the Backend made of the home trash and the trash of each mounted volume.
+type freedesktop struct {
+	home *freedesktopTrash
+	// volumes reports whether per-volume trashes are used for files outside the home trash's volume.
+	volumes bool
+}
+
+// newBackend returns the trash at trashDir, or the home trash if trashDir is empty.
+// Per-volume trashes are only used together with the home trash.
+func newBackend(trashDir string) (Backend, error) {
+	if trashDir != "" {
+		abs, err := filepath.Abs(trashDir)
+		if err != nil {
+			return nil, err
+		}
+		return &freedesktop{home: newFreedesktopTrash(abs)}, nil
+	}
+
+	home, err := homeTrashDir()
 	if err != nil {
-		fmt.Errorf("Failure to decode: %s", err)
-		return ""
+		return nil, err
 	}
-	return decodedFilePath
+	return &freedesktop{home: newFreedesktopTrash(home), volumes: true}, nil
 }
 
-func GetTrashBoxItems() ([]fi, error) {
+// homeTrashDir returns $XDG_DATA_HOME/Trash.
+// XDG_DATA_HOME defaults to ~/.local/share and must be an absolute path to be honored.
+// https://specifications.freedesktop.org/basedir-spec/latest/
+func homeTrashDir() (string, error) {
+	if dataHome := os.Getenv("XDG_DATA_HOME"); filepath.IsAbs(dataHome) {
+		return filepath.Join(dataHome, "Trash"), nil
+	}
+
 	user, err := user.Current()
 	if err != nil {
-		fmt.Errorf("Failure to get user's home directory: %s", err)
-		return nil, err
+		return "", fmt.Errorf("Failure to get user's home directory: %w", err)
+	}
+	return filepath.Join(user.HomeDir, ".local", "share", "Trash"), nil
+}
+
+func (t *freedesktopTrash) filesDir() string {
+	return filepath.Join(t.base, "files")
+}
+
+func (t *freedesktopTrash) infoDir() string {
+	return filepath.Join(t.base, "info")
+}
+
+func (t *freedesktopTrash) infoPath(trashName string) string {
+	return filepath.Join(t.infoDir(), trashName+".trashinfo")
+}
+
+func (b *freedesktop) journalPath() string {
+	return filepath.Join(b.home.base, "go-trash.journal")
+}
+
+// trashOf returns the trash directory that a path in $trash/files/ belongs to.
+func trashOf(inTrashBox string) *freedesktopTrash {
+	return newFreedesktopTrash(filepath.Dir(filepath.Dir(inTrashBox)))
+}
+
+// trashInfoPath returns the .trashinfo file of a path in $trash/files/.
+func trashInfoPath(inTrashBox string) string {
+	return trashOf(inTrashBox).infoPath(filepath.Base(inTrashBox))
+}
+
+// trashes returns the home trash followed by the trash directories found on mounted volumes.
+func (b *freedesktop) trashes() []*freedesktopTrash {
+	trashes := []*freedesktopTrash{b.home}
+	if !b.volumes {
+		return trashes
 	}
 
-	// Contents of a trash directory
-	// https://specifications.freedesktop.org/trash-spec/trashspec-1.0.html
-	trashBase := strings.Replace("~/.local/share/Trash", "~", user.HomeDir, 1)
+	for _, topdir := range mountPoints() {
+		for _, t := range volumeTrashes(topdir) {
+			if t.base != b.home.base {
+				trashes = append(trashes, t)
+			}
+		}
+	}
+	return trashes
+}
+
+func (b *freedesktop) List() ([]fi, error) {
+	var files []fi
+	for _, t := range b.trashes() {
+		tf, err := t.list()
+		if err != nil {
+			// A broken volume trash must not hide the rest of the trash.
+			if t != b.home {
+				fmt.Fprintf(os.Stderr, "Failure to read %s: %s\n", t.base, err)
+				continue
+			}
+			return nil, err
+		}
+		files = append(files, tf...)
+	}
+	return files, nil
+}
 
+func (t *freedesktopTrash) list() ([]fi, error) {
 	// Generate fullPath from .~/.local/share/Trash/files/
-	allFiles, err := ioutil.ReadDir(trashBase + "/files/")
+	allFiles, err := os.ReadDir(t.filesDir())
 	if err != nil {
-		fmt.Errorf("Failure to get files in ~/.local/share/Trash : %s", err)
-		return nil, err
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("Failure to get files in %s : %w", t.base, err)
 	}
 
+	sizes := t.readDirectorySizes()
+	sizesChanged := false
+	dirs := map[string]bool{}
+
 	var files []fi
 	for _, file := range allFiles {
-		infoFilePath := trashBase + "/info/" + file.Name() + ".trashinfo"
-		filesFilePath := trashBase + "/files/" + file.Name()
+		trashName := file.Name()
+		infoFilePath := t.infoPath(trashName)
+		filesFilePath := filepath.Join(t.filesDir(), trashName)
 
 		iFile, err := os.Open(infoFilePath)
 		if err != nil {
-			fmt.Printf("Failure to open info file: %s\n", err)
+			fmt.Fprintf(os.Stderr, "Failure to open info file: %s\n", err)
 			continue
 		}
-		defer iFile.Close()
-
-		fFile, err := os.Open(filesFilePath)
-		if err != nil {
-			fmt.Printf("Failure to open files file: %s\n", err)
-			continue
-		}
-		defer fFile.Close()
 
 		var decodedFilePath string
 		var deletedDate string
@@ -76,19 +165,31 @@ func GetTrashBoxItems() ([]fi, error) {
 		scanner := bufio.NewScanner(iFile)
 		for scanner.Scan() {
 			line := scanner.Text()
-			if pl := parseLine(line, "Path="); len(pl) > 1 {
-				decodedFilePath, _ = url.QueryUnescape(pl[1])
-			} else if pl := parseLine(line, "DeletionDate="); len(pl) > 1 {
-				deletedDate = pl[1]
+			if v, ok := strings.CutPrefix(line, "Path="); ok {
+				decodedFilePath = unescapeTrashPath(v)
+			} else if v, ok := strings.CutPrefix(line, "DeletionDate="); ok {
+				deletedDate = v
 			} else {
 				// "[Trash Info]"
 				continue
 			}
 		}
+		ist, err := iFile.Stat()
+		iFile.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failure to open info file: %s\n", err)
+			continue
+		}
 
-		fs, err := fFile.Stat()
+		// Paths in a volume trash are relative to its top directory
+		if t.topdir != "" && !filepath.IsAbs(decodedFilePath) {
+			decodedFilePath = filepath.Join(t.topdir, decodedFilePath)
+		}
+
+		fs, err := os.Lstat(filesFilePath)
 		if err != nil {
-			fmt.Printf("Failure to open file: %s\n", err)
+			fmt.Fprintf(os.Stderr, "Failure to open file: %s\n", err)
+			continue
 		}
 
 		var file fi
@@ -96,98 +197,337 @@ func GetTrashBoxItems() ([]fi, error) {
 		file.filename = filepath.Base(decodedFilePath)
 		file.location = decodedFilePath
 		file.inTrashBox = filesFilePath
-		file.dateDeleted, _ = time.Parse("2006-01-02T15:04:05Z07:00", deletedDate)
+		file.volume = t.topdir
+		file.kind = kindOf(fs.Mode())
+		file.deletionDate = deletedDate
+		file.dateDeleted, err = parseDeletionDate(deletedDate)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failure to parse DeletionDate of %s: %s\n", infoFilePath, err)
+		}
 		file.size = fs.Size()
+		if fs.IsDir() {
+			// fs.Size() of a directory is only the size of its inode
+			ds, ok := sizes[trashName]
+			if !ok || ds.mtime != ist.ModTime().Unix() {
+				ds = dirSize{treeSize(filesFilePath), ist.ModTime().Unix()}
+				sizes[trashName] = ds
+				sizesChanged = true
+			}
+			file.size = ds.size
+			dirs[trashName] = true
+		}
 		files = append(files, file)
 	}
 
+	for name := range sizes {
+		if !dirs[name] {
+			delete(sizes, name)
+			sizesChanged = true
+		}
+	}
+	if sizesChanged {
+		// The cache is only an optimization, so a read-only trash is fine
+		t.writeDirectorySizes(sizes)
+	}
+
 	return files, nil
 }
 
-func printDisplayName(line string, label string) {
-	fmt.Printf("%-12s: %s\n", label, line)
+// escapeTrashPath percent-encodes path for the Path= key of a .trashinfo file.
+// Every byte except the RFC 2396 unreserved characters and '/' is escaped,
+// so spaces, '+', '%', newlines and non-UTF-8 bytes all survive a round trip.
+func escapeTrashPath(path string) string {
+	const hex = "0123456789ABCDEF"
+	var sb strings.Builder
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		if isUnreserved(c) || c == '/' {
+			sb.WriteByte(c)
+			continue
+		}
+		sb.WriteByte('%')
+		sb.WriteByte(hex[c>>4])
+		sb.WriteByte(hex[c&0xf])
+	}
+	return sb.String()
+}
+
+// https://www.ietf.org/rfc/rfc2396.txt 2.3. Unreserved Characters
+func isUnreserved(c byte) bool {
+	switch {
+	case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		return true
+	}
+	return strings.IndexByte("-_.!~*'()", c) >= 0
 }
 
-func PrintTrashBoxItems() (ret error) {
-	files, err := GetTrashBoxItems()
+// unescapeTrashPath decodes the Path= key of a .trashinfo file.
+// It is a path, so '+' is kept as is. Some tools write unescaped paths,
+// so a value that is not valid percent-encoding is returned unchanged.
+func unescapeTrashPath(s string) string {
+	path, err := url.PathUnescape(s)
 	if err != nil {
-		return err
+		return s
 	}
+	return path
+}
+
+// DeletionDate is "YYYY-MM-DDThh:mm:ss" in the local time zone.
+const deletionDateFormat = "2006-01-02T15:04:05"
 
-	for _, file := range files {
-		fmt.Println()
-		printDisplayName(file.filename, "FileName")
-		printDisplayName(file.location, "Location")
-		printDisplayName(file.inTrashBox, "InTrashBox")
-		printDisplayName(file.dateDeleted.Format("2006-01-02T15:04:05Z07:00"), "DateDeleted")
-		printDisplayName(strconv.FormatInt(file.size, 10), "Size")
+// parseDeletionDate parses the DeletionDate= key of a .trashinfo file.
+// Besides the spec's local time format, RFC 3339 dates with a time zone are accepted,
+// as written by older versions of go-trash and some other tools.
+func parseDeletionDate(s string) (time.Time, error) {
+	if t, err := time.ParseInLocation(deletionDateFormat, s, time.Local); err == nil {
+		return t, nil
 	}
-	return nil
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t.Local(), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", s)
+}
+
+func formatDeletionDate(t time.Time) string {
+	return t.Local().Format(deletionDateFormat)
 }
 
 func convertTrashInfo(i Info) string {
-	return fmt.Sprintf("[Trash Info]\nPath=%s\nDeletionDate=%s\n", i.path, i.deletionDate.UTC().Format(time.RFC3339))
+	return fmt.Sprintf("[Trash Info]\nPath=%s\nDeletionDate=%s\n", escapeTrashPath(i.path), formatDeletionDate(i.deletionDate))
 }
 
-func MoveToTrashBox(path string) (err error) {
-	filename := filepath.Base(path)
-	abs, err := filepath.Abs(path)
+// trashFor returns the trash that path should be moved into and the path to record in it:
+// the home trash if path is on the same volume, otherwise the trash of path's volume.
+// Symbolic links in the directories leading to path are resolved first, so that an item
+// reached through a link to another volume goes to a trash that List scans, under its real path.
+// If the volume has no usable trash, the home trash is returned and put falls back to copying.
+func (b *freedesktop) trashFor(path string) (*freedesktopTrash, string) {
+	if !b.volumes {
+		return b.home, path
+	}
+
+	if err := os.MkdirAll(b.home.base, 0700); err != nil {
+		return b.home, path
+	}
+	real, err := resolveParents(path)
 	if err != nil {
-		return err
+		return b.home, path
+	}
+	if same, err := sameDevice(real, b.home.base); err != nil || same {
+		return b.home, path
 	}
 
-	info := Info{abs, time.Now()}
-	user, err := user.Current()
+	topdir, err := topDir(real)
+	if err != nil || !isMountPoint(topdir) {
+		return b.home, path
+	}
+	t, err := volumeTrash(topdir)
 	if err != nil {
-		return err
+		return b.home, path
+	}
+	return t, real
+}
+
+func (b *freedesktop) Put(path string) (fi, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return fi{}, err
+	}
+	if _, err := os.Lstat(abs); err != nil {
+		return fi{}, err
+	}
+
+	t, abs := b.trashFor(abs)
+	return t.put(abs)
+}
+
+// createInfoFile reserves a name in the trash by creating its .trashinfo file with O_EXCL,
+// so that two items never share a name. If the original name is taken,
+// "name.2", "name.3", ... are tried. The reserved trash name is returned.
+func (t *freedesktopTrash) createInfoFile(name string, content string) (string, error) {
+	for n := 1; ; n++ {
+		trashName := name
+		if n > 1 {
+			trashName = name + "." + strconv.Itoa(n)
+		}
+
+		f, err := os.OpenFile(t.infoPath(trashName), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+
+		// A leftover in files/ without an info file must not be overwritten either
+		if _, err := os.Lstat(filepath.Join(t.filesDir(), trashName)); err == nil {
+			f.Close()
+			os.Remove(t.infoPath(trashName))
+			continue
+		}
+
+		_, err = f.WriteString(content)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			os.Remove(t.infoPath(trashName))
+			return "", err
+		}
+		return trashName, nil
 	}
+}
 
-	trashBase := strings.Replace("~/.local/share/Trash", "~", user.HomeDir, 1)
-	if _, err := os.Stat(trashBase + "/info/"); err != nil {
-		os.MkdirAll(trashBase+"/info/", os.ModePerm)
+func (t *freedesktopTrash) put(abs string) (fi, error) {
+	infoPath := abs
+	if t.topdir != "" {
+		rel, err := filepath.Rel(t.topdir, abs)
+		if err != nil {
+			return fi{}, err
+		}
+		infoPath = rel
 	}
+	// Whole seconds, as in the info file, so that the returned item is the one List finds
+	info := Info{infoPath, time.Now().Truncate(time.Second)}
 
-	if _, err := os.Stat(trashBase + "/files/"); err != nil {
-		os.MkdirAll(trashBase+"/files/", os.ModePerm)
+	if _, err := os.Stat(t.infoDir()); err != nil {
+		os.MkdirAll(t.infoDir(), 0700)
 	}
 
-	err = os.WriteFile(trashBase+"/info/"+filename+".trashinfo", []byte(convertTrashInfo(info)), os.ModePerm)
+	if _, err := os.Stat(t.filesDir()); err != nil {
+		os.MkdirAll(t.filesDir(), 0700)
+	}
+
+	trashName, err := t.createInfoFile(filepath.Base(abs), convertTrashInfo(info))
 	if err != nil {
-		return err
+		return fi{}, err
 	}
 
-	// May not be able to move files or directories between different partitions
-	// Occur "Invalid cross-device link error"
-	// https://stackoverflow.com/questions/42392600/oserror-errno-18-invalid-cross-device-link
-	err = os.Rename(path, trashBase+"/files/"+filename)
+	inTrashBox := filepath.Join(t.filesDir(), trashName)
+	err = os.Rename(abs, inTrashBox)
+	copied := false
+	if errors.Is(err, syscall.EXDEV) {
+		// Files or directories cannot be renamed between different partitions, so copy them instead
+		err = copyVerified(abs, inTrashBox)
+		copied = err == nil
+	}
 	if err != nil {
-		return err
+		// The file did not make it to the trash, so its info file must not stay behind.
+		os.Remove(t.infoPath(trashName))
+		return fi{}, err
 	}
 
-	return nil
+	file := fi{
+		filename:     filepath.Base(abs),
+		location:     abs,
+		inTrashBox:   inTrashBox,
+		dateDeleted:  info.deletionDate,
+		deletionDate: formatDeletionDate(info.deletionDate),
+	}
+	t.updateDirectorySize(trashName)
+	if copied {
+		if err := os.RemoveAll(abs); err != nil {
+			return file, fmt.Errorf("%s was copied to the trash but could not be removed: %w", abs, err)
+		}
+	}
+	return file, nil
 }
 
-func Undelete(srcPath string, dstPath string) (err error) {
-	user, err := user.Current()
+func (b *freedesktop) Restore(file fi, dstPath string) error {
+	err := os.Rename(file.inTrashBox, dstPath)
+	if errors.Is(err, syscall.EXDEV) {
+		// Items copied into the home trash from another volume are copied back the same way
+		err = copyVerified(file.inTrashBox, dstPath)
+		if err == nil {
+			err = os.RemoveAll(file.inTrashBox)
+		}
+	}
 	if err != nil {
 		return err
 	}
 
-	// Contents of a trash directory
-	// https://specifications.freedesktop.org/trash-spec/trashspec-1.0.html
-	trashBase := strings.Replace("~/.local/share/Trash", "~", user.HomeDir, 1)
-	infoFilePath := trashBase + "/info/" + filepath.Base(srcPath) + ".trashinfo"
+	// /info/ file is still in the trash box. So deleted it.
+	err = os.Remove(trashInfoPath(file.inTrashBox))
+	if err != nil {
+		return err
+	}
 
-	err = os.Rename(srcPath, dstPath)
+	trashOf(file.inTrashBox).updateDirectorySize(filepath.Base(file.inTrashBox))
+	return nil
+}
+
+func (b *freedesktop) Purge(file fi) error {
+	err := os.RemoveAll(file.inTrashBox)
 	if err != nil {
 		return err
 	}
 
-	// /info/ file is still in the trash box. So deleted it.
-	err = os.Remove(infoFilePath)
+	err = os.Remove(trashInfoPath(file.inTrashBox))
 	if err != nil {
 		return err
 	}
 
+	trashOf(file.inTrashBox).updateDirectorySize(filepath.Base(file.inTrashBox))
 	return nil
 }
+
+// emptied returns the trashes --empty clears: the home trash, and the volume trashes with allVolumes.
+func (b *freedesktop) emptied(allVolumes bool) []*freedesktopTrash {
+	if !allVolumes {
+		return []*freedesktopTrash{b.home}
+	}
+	return b.trashes()
+}
+
+func (b *freedesktop) entryCount(allVolumes bool) int {
+	var n int
+	for _, t := range b.emptied(allVolumes) {
+		for _, dir := range []string{t.filesDir(), t.infoDir()} {
+			entries, _ := os.ReadDir(dir)
+			n += len(entries)
+		}
+	}
+	return n
+}
+
+func (b *freedesktop) clear(allVolumes bool) (int64, error) {
+	var reclaimed int64
+	var errs []error
+	for _, t := range b.emptied(allVolumes) {
+		n, err := t.clear()
+		reclaimed += n
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return reclaimed, errors.Join(errs...)
+}
+
+// clear permanently deletes everything in files/ and info/ and the directory size cache,
+// including the entries list skips: files without an info file and info files without a file.
+func (t *freedesktopTrash) clear() (int64, error) {
+	var reclaimed int64
+	var errs []error
+	for _, dir := range []string{t.filesDir(), t.infoDir()} {
+		entries, err := os.ReadDir(dir)
+		if err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err)
+			continue
+		}
+		for _, entry := range entries {
+			path := filepath.Join(dir, entry.Name())
+			size := treeSize(path)
+			if err := os.RemoveAll(path); err != nil {
+				errs = append(errs, fmt.Errorf("cannot purge '%s': %w", path, err))
+				continue
+			}
+			if dir == t.filesDir() {
+				reclaimed += size
+			}
+		}
+	}
+	if err := os.Remove(t.directorySizesPath()); err != nil && !os.IsNotExist(err) {
+		errs = append(errs, err)
+	}
+	return reclaimed, errors.Join(errs...)
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTrashPathRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		escaped string
+	}{
+		{"plain", "/home/user/a.txt", "/home/user/a.txt"},
+		{"space", "/home/user/my file.txt", "/home/user/my%20file.txt"},
+		{"plus", "/home/user/c++/a+b", "/home/user/c%2B%2B/a%2Bb"},
+		{"percent", "/home/user/100%.txt", "/home/user/100%25.txt"},
+		{"escaped percent", "/home/user/%41", "/home/user/%2541"},
+		{"newline", "/home/user/a\nb", "/home/user/a%0Ab"},
+		{"utf-8", "/home/user/あ", "/home/user/%E3%81%82"},
+		{"non utf-8", "/home/user/\xff\xfe", "/home/user/%FF%FE"},
+		{"unreserved", "/home/user/-_.!~*'()", "/home/user/-_.!~*'()"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			escaped := escapeTrashPath(tt.path)
+			if escaped != tt.escaped {
+				t.Errorf("escapeTrashPath(%q) = %q, want %q", tt.path, escaped, tt.escaped)
+			}
+			if got := unescapeTrashPath(escaped); got != tt.path {
+				t.Errorf("unescapeTrashPath(%q) = %q, want %q", escaped, got, tt.path)
+			}
+		})
+	}
+}
+
+// Other tools write Path= values unescaped, or escaped with lowercase hex digits.
+func TestUnescapeTrashPathFromOtherTools(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"raw space", "/home/user/my file.txt", "/home/user/my file.txt"},
+		{"raw plus", "/home/user/a+b", "/home/user/a+b"},
+		{"raw percent", "/home/user/100%.txt", "/home/user/100%.txt"},
+		{"raw utf-8", "/home/user/あ", "/home/user/あ"},
+		{"lowercase hex", "/home/user/%e3%81%82", "/home/user/あ"},
+		{"lowercase plus", "/home/user/a%2bb", "/home/user/a+b"},
+		{"lowercase non utf-8", "/home/user/%ff", "/home/user/\xff"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := unescapeTrashPath(tt.value); got != tt.want {
+				t.Errorf("unescapeTrashPath(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListReadsForeignPaths(t *testing.T) {
+	trash := newFreedesktopTrash(t.TempDir())
+	for _, dir := range []string{trash.filesDir(), trash.infoDir()} {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	infos := map[string]string{
+		"raw":   "/home/user/my file+1.txt",
+		"lower": "/home/user/%e3%81%82%20b",
+	}
+	for name, path := range infos {
+		content := "[Trash Info]\nPath=" + path + "\nDeletionDate=2024-01-02T03:04:05\n"
+		if err := os.WriteFile(trash.infoPath(name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(trash.filesDir(), name), nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := trash.list()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"raw":   "/home/user/my file+1.txt",
+		"lower": "/home/user/あ b",
+	}
+	if len(files) != len(want) {
+		t.Fatalf("list() returned %d items, want %d", len(files), len(want))
+	}
+	for _, file := range files {
+		name := filepath.Base(file.inTrashBox)
+		if file.location != want[name] {
+			t.Errorf("location of %s = %q, want %q", name, file.location, want[name])
+		}
+		if deleted := time.Date(2024, 1, 2, 3, 4, 5, 0, time.Local); !file.dateDeleted.Equal(deleted) {
+			t.Errorf("dateDeleted of %s = %v, want %v", name, file.dateDeleted, deleted)
+		}
+	}
+}
+
+func TestPutRoundTrip(t *testing.T) {
+	trash := newFreedesktopTrash(t.TempDir())
+	dir := t.TempDir()
+	for _, name := range []string{"my file.txt", "a+b", "100%", "a\nb", "\xff\xfe"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(name), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := trash.put(path); err != nil {
+			t.Fatalf("put(%q): %v", path, err)
+		}
+	}
+
+	files, err := trash.list()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 5 {
+		t.Fatalf("list() returned %d items, want 5", len(files))
+	}
+	for _, file := range files {
+		if want := filepath.Join(dir, file.filename); file.location != want {
+			t.Errorf("location = %q, want %q", file.location, want)
+		}
+	}
+}
+
+// The DeletionDate= of the spec has no time zone, so the ID of an item
+// must not change with the time zone the trash is listed in.
+func TestStableIDIgnoresTimeZone(t *testing.T) {
+	trash := newFreedesktopTrash(t.TempDir())
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tz1")
+	if err := os.WriteFile(path, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	put, err := trash.put(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(local *time.Location) { time.Local = local }(time.Local)
+	for _, zone := range []string{"UTC", "Asia/Tokyo", "America/New_York"} {
+		loc, err := time.LoadLocation(zone)
+		if err != nil {
+			t.Skip(err)
+		}
+		time.Local = loc
+		files, err := trash.list()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(files) != 1 || stableID(files[0]) != stableID(put) {
+			t.Errorf("in %s, listed %v, want the ID %s of the trashed item", zone, files, stableID(put))
+		}
+	}
+}
+
+// An undo run in another time zone than the trash command must still find its files,
+// and must not undo an older command instead.
+func TestUndoBatchIgnoresTimeZone(t *testing.T) {
+	b := &freedesktop{home: newFreedesktopTrash(t.TempDir())}
+	dir := t.TempDir()
+	trash := func(name string) {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+		file, err := b.Put(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := recordBatch(b, []fi{file}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	defer func(local *time.Location) { time.Local = local }(time.Local)
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skip(err)
+	}
+	time.Local = tokyo
+	trash("w")
+	trash("tz1")
+
+	time.Local = time.UTC
+	if err := undoBatch(b, 1, restoreOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Lstat(filepath.Join(dir, "tz1")); err != nil {
+		t.Errorf("tz1 was not restored: %v", err)
+	}
+	if _, err := os.Lstat(filepath.Join(dir, "w")); err == nil {
+		t.Error("w of the older command was restored")
+	}
+}
+
+// Items with the same name must get their own trash names instead of overwriting each other.
+func TestPutSameNameTwice(t *testing.T) {
+	trash := newFreedesktopTrash(t.TempDir())
+	b := &freedesktop{home: trash}
+	dir := t.TempDir()
+	for _, sub := range []string{"a", "b"} {
+		path := filepath.Join(dir, sub, "notes.txt")
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(sub), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := b.Put(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, name := range []string{"notes.txt", "notes.txt.2"} {
+		if _, err := os.Lstat(filepath.Join(trash.filesDir(), name)); err != nil {
+			t.Errorf("files/%s: %v", name, err)
+		}
+		if _, err := os.Lstat(trash.infoPath(name)); err != nil {
+			t.Errorf("info/%s.trashinfo: %v", name, err)
+		}
+	}
+
+	files, err := b.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("List() returned %d items, want 2", len(files))
+	}
+	for _, file := range files {
+		if err := b.Restore(file, file.location); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, sub := range []string{"a", "b"} {
+		content, err := os.ReadFile(filepath.Join(dir, sub, "notes.txt"))
+		if err != nil || string(content) != sub {
+			t.Errorf("restored %s/notes.txt = %q, %v, want %q", sub, content, err, sub)
+		}
+	}
+}
+
+// A file left in files/ without its .trashinfo must not be overwritten either.
+func TestPutSkipsLeftoverFile(t *testing.T) {
+	trash := newFreedesktopTrash(t.TempDir())
+	if err := os.MkdirAll(trash.filesDir(), 0700); err != nil {
+		t.Fatal(err)
+	}
+	leftover := filepath.Join(trash.filesDir(), "notes.txt")
+	if err := os.WriteFile(leftover, []byte("leftover"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(path, []byte("new"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	file, err := trash.put(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(trash.filesDir(), "notes.txt.2"); file.inTrashBox != want {
+		t.Errorf("inTrashBox = %q, want %q", file.inTrashBox, want)
+	}
+	if content, err := os.ReadFile(leftover); err != nil || string(content) != "leftover" {
+		t.Errorf("leftover = %q, %v, want it untouched", content, err)
+	}
+	if _, err := os.Lstat(trash.infoPath("notes.txt")); !os.IsNotExist(err) {
+		t.Errorf("info file of the leftover was created: %v", err)
+	}
+}
@@ -7,7 +7,6 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -406,7 +405,19 @@ func getFileSize(rbInternalFormat []byte) int64 {
 	return fsize
 }
 
-func GetTrashBoxItems() ([]fi, error) {
+// recycleBin is the Windows Recycle Bin, driven through the shell API.
+type recycleBin struct{}
+
+// newBackend returns the Recycle Bin. Its location is managed by Windows,
+// so a trash directory cannot be specified.
+func newBackend(trashDir string) (Backend, error) {
+	if trashDir != "" {
+		return nil, errors.New("a trash directory cannot be specified for the Recycle Bin")
+	}
+	return recycleBin{}, nil
+}
+
+func (recycleBin) List() ([]fi, error) {
 	ret, _ := _CoInitialize(uintptr(0))
 	if ret != 0 {
 		// Call FormatMessage API to display correct errors.
@@ -442,6 +453,9 @@ func GetTrashBoxItems() ([]fi, error) {
 		GetDisplayName(&file, pRecycleBinFolder, pItemIDL, SHGDN_FORPARSING, "ForParsing")  // file name in $RECYCLE.BIN
 		GetDisplayName(&file, pRecycleBinFolder, pItemIDL, SHGDN_FORPARSING, "DateDeleted") // date deleted
 		GetDisplayName(&file, pRecycleBinFolder, pItemIDL, SHGDN_FORPARSING, "Size")        // file size
+		if st, err := os.Lstat(file.inTrashBox); err == nil {
+			file.kind = kindOf(st.Mode())
+		}
 		files = append(files, file)
 
 		CoTaskMemFree(uintptr(unsafe.Pointer(pItemIDL)))
@@ -469,7 +483,9 @@ func GetDisplayName(file *fi, psf *IShellFolder, pidl *ITEMIDLIST, uFlags uint32
 		f.Read(buf)
 
 		if strings.Contains(label, "DateDelete") {
-			file.dateDeleted = getDateDelete(buf).Local()
+			deleted := getDateDelete(buf)
+			file.dateDeleted = deleted.Local()
+			file.deletionDate = deleted.UTC().Format(time.RFC3339Nano)
 		} else if strings.Contains(label, "Size") {
 			file.size = getFileSize(buf)
 		}
@@ -502,42 +518,32 @@ func GetRecycleBinShellFolder(pRecycleBinFolder **IShellFolder) (ret uintptr, er
 	return ret, err
 }
 
-func PrintTrashBoxItems() error {
-	files, err := GetTrashBoxItems()
-	if err != nil {
-		return err
-	}
-
-	for _, file := range files {
-		fmt.Println()
-		PrintDisplayName(file.filename, "FileName")
-		PrintDisplayName(file.location, "Location")
-		PrintDisplayName(file.inTrashBox, "InTrashBox")
-		PrintDisplayName(file.dateDeleted.Format("2006-01-02T15:04:05Z07:00"), "DateDeleted")
-		PrintDisplayName(strconv.FormatInt(file.size, 10), "Size")
-	}
-
-	return nil
-}
-
-func PrintDisplayName(line string, label string) {
-	fmt.Printf("%-12s: %s\n", label, line)
+// recycleInfoPath returns the $I file that holds the metadata of a $R file.
+func recycleInfoPath(rPath string) string {
+	return filepath.Join(filepath.Dir(rPath), strings.Replace(filepath.Base(rPath), "$R", "$I", 1))
 }
 
-func Undelete(srcPath string, dstPath string) error {
-	r := os.Rename(srcPath, dstPath)
+func (recycleBin) Restore(file fi, dstPath string) error {
+	r := os.Rename(file.inTrashBox, dstPath)
 	if r != nil {
 		return r
 	}
 
 	// $I file is still in the trash box. So deleted it.
-	recycleDir := filepath.Dir(srcPath)
-	ipath := strings.Replace(srcPath, "$R", "$I", 1)
-	os.Remove(recycleDir + "\\" + ipath)
+	os.Remove(recycleInfoPath(file.inTrashBox))
 
 	return nil
 }
 
+func (recycleBin) Purge(file fi) error {
+	err := os.RemoveAll(file.inTrashBox)
+	if err != nil {
+		return err
+	}
+
+	return os.Remove(recycleInfoPath(file.inTrashBox))
+}
+
 func isMatchFilename(psf *IShellFolder, pidl *ITEMIDLIST, file string) bool {
 	var pName STRRET
 	ret := psf.GetDisplayNameOf(pidl, SHGDN_NORMAL, &pName)
@@ -577,7 +583,12 @@ func _SHFileOperation(
 	return
 }
 
-func MoveToTrashBox(path string) (err error) {
+func (recycleBin) Put(path string) (fi, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return fi{}, err
+	}
+
 	var fileOp SHFILEOPSTRUCT
 	fileOp.Hwnd = uintptr(0)
 	fileOp.Func = FO_DELETE
@@ -588,7 +599,11 @@ func MoveToTrashBox(path string) (err error) {
 	ret, _ := _SHFileOperation(&fileOp)
 	if ret != 0 {
 		// Call FormatMessage API to display correct errors.
-		return _FormatMessage(ret)
+		return fi{}, _FormatMessage(ret)
 	}
-	return nil
+	return fi{
+		filename:    filepath.Base(abs),
+		location:    abs,
+		dateDeleted: time.Now(),
+	}, nil
 }
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// Per-volume trash directories
+// https://specifications.freedesktop.org/trash-spec/trashspec-1.0.html#id-1.6.5
+
+// mountPoints returns the mount points listed in /proc/self/mounts.
+func mountPoints() []string {
+	f, err := os.Open("/proc/self/mounts")
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	seen := map[string]bool{}
+	var mounts []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		mount := unescapeMountPoint(fields[1])
+		if !seen[mount] {
+			seen[mount] = true
+			mounts = append(mounts, mount)
+		}
+	}
+	return mounts
+}
+
+// unescapeMountPoint decodes the octal escapes (\040 for a space, ...) used in /proc/self/mounts.
+func unescapeMountPoint(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+4 <= len(s) {
+			if n, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				sb.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
+
+// isMountPoint reports whether dir is listed in /proc/self/mounts, so that List scans its trash.
+func isMountPoint(dir string) bool {
+	for _, mount := range mountPoints() {
+		if mount == dir {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveParents resolves the symbolic links in the directories leading to path.
+// path itself is not followed, as a symbolic link is trashed, not its target.
+func resolveParents(path string) (string, error) {
+	dir, err := filepath.EvalSymlinks(filepath.Dir(path))
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, filepath.Base(path)), nil
+}
+
+func deviceOf(path string) (uint64, error) {
+	var st syscall.Stat_t
+	if err := syscall.Lstat(path, &st); err != nil {
+		return 0, &os.PathError{Op: "lstat", Path: path, Err: err}
+	}
+	return uint64(st.Dev), nil
+}
+
+// sameDevice reports whether a and b are on the same volume.
+func sameDevice(a string, b string) (bool, error) {
+	da, err := deviceOf(a)
+	if err != nil {
+		return false, err
+	}
+	db, err := deviceOf(b)
+	if err != nil {
+		return false, err
+	}
+	return da == db, nil
+}
+
+// topDir returns the mount point of the volume that path is on.
+func topDir(path string) (string, error) {
+	dev, err := deviceOf(path)
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Dir(path)
+	for {
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir, nil
+		}
+		pdev, err := deviceOf(parent)
+		if err != nil {
+			return "", err
+		}
+		if pdev != dev {
+			return dir, nil
+		}
+		dir = parent
+	}
+}
+
+// checkAdminTrash reports whether $topdir/.Trash can hold per-user trashes:
+// it must be a real directory, not a symbolic link, with the sticky bit set.
+func checkAdminTrash(dir string) error {
+	st, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	if st.Mode()&os.ModeSymlink != 0 {
+		return fmt.Errorf("%s is a symbolic link", dir)
+	}
+	if !st.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	if st.Mode()&os.ModeSticky == 0 {
+		return fmt.Errorf("%s does not have the sticky bit set", dir)
+	}
+	return nil
+}
+
+// checkUserTrash reports whether dir is a directory owned by the current user and not a symbolic link.
+func checkUserTrash(dir string) error {
+	st, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	if st.Mode()&os.ModeSymlink != 0 {
+		return fmt.Errorf("%s is a symbolic link", dir)
+	}
+	if !st.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	if sys, ok := st.Sys().(*syscall.Stat_t); ok && int(sys.Uid) != os.Getuid() {
+		return fmt.Errorf("%s is not owned by the current user", dir)
+	}
+	return nil
+}
+
+// volumeTrashes returns the existing trash directories of the volume mounted at topdir.
+func volumeTrashes(topdir string) []*freedesktopTrash {
+	uid := strconv.Itoa(os.Getuid())
+
+	var trashes []*freedesktopTrash
+	if checkAdminTrash(filepath.Join(topdir, ".Trash")) == nil {
+		dir := filepath.Join(topdir, ".Trash", uid)
+		if checkUserTrash(dir) == nil {
+			trashes = append(trashes, &freedesktopTrash{base: dir, topdir: topdir})
+		}
+	}
+	dir := filepath.Join(topdir, ".Trash-"+uid)
+	if checkUserTrash(dir) == nil {
+		trashes = append(trashes, &freedesktopTrash{base: dir, topdir: topdir})
+	}
+	return trashes
+}
+
+// volumeTrash returns the trash to put files on the volume mounted at topdir into.
+// $topdir/.Trash/$uid is used when the administrator has set up $topdir/.Trash,
+// otherwise $topdir/.Trash-$uid is used, creating it if needed.
+func volumeTrash(topdir string) (*freedesktopTrash, error) {
+	uid := strconv.Itoa(os.Getuid())
+
+	if checkAdminTrash(filepath.Join(topdir, ".Trash")) == nil {
+		dir := filepath.Join(topdir, ".Trash", uid)
+		if err := os.Mkdir(dir, 0700); err == nil || errors.Is(err, os.ErrExist) {
+			if checkUserTrash(dir) == nil {
+				return &freedesktopTrash{base: dir, topdir: topdir}, nil
+			}
+		}
+	}
+
+	dir := filepath.Join(topdir, ".Trash-"+uid)
+	if err := os.Mkdir(dir, 0700); err != nil && !errors.Is(err, os.ErrExist) {
+		return nil, fmt.Errorf("Failure to create trash on %s: %w", topdir, err)
+	}
+	if err := checkUserTrash(dir); err != nil {
+		return nil, err
+	}
+	return &freedesktopTrash{base: dir, topdir: topdir}, nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestResolveParents(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	real := filepath.Join(dir, "real")
+	if err := os.Mkdir(real, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(real, filepath.Join(dir, "lnk")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{filepath.Join(dir, "lnk", "sf"), filepath.Join(real, "sf")},
+		{filepath.Join(dir, "real", "sf"), filepath.Join(real, "sf")},
+		// The link itself is trashed, not its target
+		{filepath.Join(dir, "lnk"), filepath.Join(dir, "lnk")},
+	}
+	for _, tt := range tests {
+		got, err := resolveParents(tt.path)
+		if err != nil {
+			t.Fatalf("resolveParents(%q): %v", tt.path, err)
+		}
+		if got != tt.want {
+			t.Errorf("resolveParents(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+// A file reached through a symbolic link to another volume goes to the trash of that volume,
+// recorded under its real path so that List finds it again.
+func TestPutThroughSymlinkToOtherVolume(t *testing.T) {
+	home := t.TempDir()
+	real, err := os.MkdirTemp("/dev/shm", "go-trash-test")
+	if err != nil {
+		t.Skip("/dev/shm is not available")
+	}
+	defer os.RemoveAll(real)
+	if real, err = filepath.EvalSymlinks(real); err != nil {
+		t.Fatal(err)
+	}
+	topdir, err := topDir(real)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if same, err := sameDevice(home, real); err != nil || same || !isMountPoint(topdir) {
+		t.Skip("/dev/shm is not a separate volume")
+	}
+	trashDir := filepath.Join(topdir, ".Trash-"+strconv.Itoa(os.Getuid()))
+	if _, err := os.Lstat(trashDir); os.IsNotExist(err) {
+		defer os.RemoveAll(trashDir)
+	}
+
+	link := filepath.Join(t.TempDir(), "lnk")
+	if err := os.Symlink(real, link); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(real, "sf"), []byte("sf"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	b := &freedesktop{home: newFreedesktopTrash(filepath.Join(home, "Trash")), volumes: true}
+	put, err := b.Put(filepath.Join(link, "sf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(real, "sf"); put.location != want {
+		t.Errorf("location = %q, want %q", put.location, want)
+	}
+
+	files, err := b.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		if file.inTrashBox == put.inTrashBox {
+			if file.location != put.location {
+				t.Errorf("listed location = %q, want %q", file.location, put.location)
+			}
+			if file.volume != topdir {
+				t.Errorf("listed volume = %q, want %q", file.volume, topdir)
+			}
+			if err := b.Purge(file); err != nil {
+				t.Error(err)
+			}
+			return
+		}
+	}
+	t.Errorf("%s was trashed to %s but is not listed", put.location, put.inTrashBox)
+}